@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 	"vote-bot/internal/bot"
 	"vote-bot/internal/config"
+	memoryrepo "vote-bot/internal/repo/memory"
+	tarantoolrepo "vote-bot/internal/repo/tarantool"
+	"vote-bot/internal/service"
 	"vote-bot/pkg/sl"
 	"vote-bot/pkg/tarantool"
+
+	tt "github.com/tarantool/go-tarantool/v2"
 )
 
 func main() {
@@ -21,18 +30,32 @@ func main() {
 	log.Info(
 		"starting bot...",
 		slog.String("env", cfg.Env),
+		slog.String("repo_driver", cfg.Repo.Driver),
 	)
 
-	log.Info("initializing connection to tarantool...")
-	conn, err := tarantool.NewConn(cfg.Tarantool)
-	if err != nil {
-		log.Error("failed to connect to tarantool", sl.Error(err))
-		os.Exit(1)
+	var (
+		repo service.Repo
+		conn *tt.Connection
+	)
+	switch cfg.Repo.Driver {
+	case config.RepoDriverMemory:
+		log.Warn("using in-memory repo, polls will be lost on restart")
+		repo = memoryrepo.NewRepo()
+	default:
+		log.Info("initializing connection to tarantool...")
+		var err error
+		conn, err = tarantool.NewConn(cfg.Tarantool)
+		if err != nil {
+			log.Error("failed to connect to tarantool", sl.Error(err))
+			os.Exit(1)
+		}
+		log.Info("connected to tarantool")
+
+		repo = tarantoolrepo.NewRepo(conn)
 	}
-	log.Info("connected to tarantool")
 
 	log.Info("initializing bot...")
-	bot, err := bot.NewBot(log, cfg.Mattermost, conn)
+	bot, err := bot.NewBot(log, cfg, repo)
 	if err != nil {
 		log.Error("failed to init bot", sl.Error(err))
 		os.Exit(1)
@@ -43,16 +66,34 @@ func main() {
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 
 	log.Info("starting bot...")
-	go bot.Client.ListenToEvents()
+	bot.Start()
+
+	log.Info("starting http server...", slog.String("address", cfg.HTTPServer.Address))
+	go func() {
+		if err := bot.Server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("failed to start http server", sl.Error(err))
+			stop <- syscall.SIGTERM
+		}
+	}()
 
 	<-stop
 	log.Info("stopping app")
 
-	bot.Client.StopListening()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := bot.Server.Shutdown(ctx); err != nil {
+		log.Error("failed to stop http server", sl.Error(err))
+	}
+	log.Info("stopped http server")
+
+	bot.Stop()
 	log.Info("bot doesn't listening for events anymore")
 
-	tarantool.CloseConn(conn)
-	log.Info("closed connection to tarantool")
+	if conn != nil {
+		tarantool.CloseConn(conn)
+		log.Info("closed connection to tarantool")
+	}
 
 	log.Info("stopped bot")
 
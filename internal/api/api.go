@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vote-bot/internal/config"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/service"
+	"vote-bot/pkg/sl"
+)
+
+// BasePath is a prefix of all REST API endpoints.
+const BasePath = "/api/v1"
+
+// maxBodySize limits size of request bodies.
+const maxBodySize = 1 << 20
+
+//go:embed openapi.yaml
+var openAPIDoc []byte
+
+type PollService interface {
+	CreatePoll(ctx context.Context, poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error)
+	GetPoll(ctx context.Context, pollID uint64, channel string) (*entity.Poll, error)
+	GetOptions(ctx context.Context, pollID uint64, channel string) ([]entity.Option, error)
+	FinishPoll(ctx context.Context, pollID uint64, user string, channel string) error
+	DeletePoll(ctx context.Context, pollID uint64, user string, channel string) error
+}
+
+type VoteService interface {
+	Vote(ctx context.Context, pollID uint64, user string, channel string, opts []uint64, scores []uint64) (string, error)
+	RetractVote(ctx context.Context, pollID uint64, user string, channel string, receipt string) error
+	GetResults(ctx context.Context, pollID uint64, channel string) (*entity.Results, error)
+	GetTally(ctx context.Context, pollID uint64, channel string) (*entity.Results, error)
+}
+
+type Scheduler interface {
+	Schedule(poll entity.Poll)
+	Cancel(pollID uint64)
+}
+
+// Notifier keeps poll posts in chat up to date with changes made via API.
+type Notifier interface {
+	AnnouncePoll(ctx context.Context, poll *entity.Poll, options []entity.Option) error
+	RefreshPollPost(ctx context.Context, pollID uint64, channel string) bool
+}
+
+// API serves JSON endpoints over poll and vote services.
+type API struct {
+	log       *slog.Logger
+	polls     PollService
+	votes     VoteService
+	scheduler Scheduler
+	notifier  Notifier
+	// timeout limits handling of one request.
+	timeout time.Duration
+
+	tokens []apiToken
+}
+
+// apiToken authenticates requests made on behalf of the user.
+type apiToken struct {
+	user  string
+	token string
+}
+
+func New(
+	cfg config.API,
+	requests config.Requests,
+	log *slog.Logger,
+	polls PollService,
+	votes VoteService,
+	scheduler Scheduler,
+	notifier Notifier,
+) (*API, error) {
+	const op = "api.New"
+
+	tokens := make([]apiToken, 0, len(cfg.Tokens))
+	for _, pair := range cfg.Tokens {
+		user, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || user == "" || token == "" {
+			return nil, fmt.Errorf("%s: api token must be in user_id:token format", op)
+		}
+		tokens = append(tokens, apiToken{user: user, token: token})
+	}
+
+	return &API{
+		log:       log,
+		polls:     polls,
+		votes:     votes,
+		scheduler: scheduler,
+		notifier:  notifier,
+		timeout:   requests.Timeout,
+		tokens:    tokens,
+	}, nil
+}
+
+// Enabled reports whether API has tokens to authenticate requests with.
+func (a *API) Enabled() bool {
+	return len(a.tokens) != 0
+}
+
+// RegisterHandlers registers API endpoints in case API is enabled.
+func (a *API) RegisterHandlers(mux *http.ServeMux) {
+	if !a.Enabled() {
+		a.log.Warn("no api tokens configured, rest api is disabled")
+		return
+	}
+
+	mux.HandleFunc("GET "+BasePath+"/openapi.yaml", a.handleOpenAPI)
+
+	mux.HandleFunc("POST "+BasePath+"/polls", a.auth(a.handleCreatePoll))
+	mux.HandleFunc("GET "+BasePath+"/polls/{id}", a.auth(a.handleGetPoll))
+	mux.HandleFunc("DELETE "+BasePath+"/polls/{id}", a.auth(a.handleDeletePoll))
+	mux.HandleFunc("GET "+BasePath+"/polls/{id}/options", a.auth(a.handleGetOptions))
+	mux.HandleFunc("POST "+BasePath+"/polls/{id}/finish", a.auth(a.handleFinishPoll))
+	mux.HandleFunc("PUT "+BasePath+"/polls/{id}/vote", a.auth(a.handleVote))
+	mux.HandleFunc("DELETE "+BasePath+"/polls/{id}/vote", a.auth(a.handleRetractVote))
+	mux.HandleFunc("GET "+BasePath+"/polls/{id}/results", a.auth(a.handleGetResults))
+}
+
+// authHandlerFunc is a handler of request made on behalf of the user.
+type authHandlerFunc func(w http.ResponseWriter, r *http.Request, user string)
+
+// auth checks bearer token of the request and passes its user to the handler.
+// Context of the request gets timeout from config.
+func (a *API) auth(next authHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		// All tokens are compared to keep constant time.
+		var user string
+		for _, t := range a.tokens {
+			if subtle.ConstantTimeCompare([]byte(t.token), []byte(token)) == 1 {
+				user = t.user
+			}
+		}
+		if user == "" {
+			a.log.Warn("got api request with invalid token", slog.String("path", r.URL.Path))
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), a.timeout)
+		defer cancel()
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		next(w, r.WithContext(ctx), user)
+	}
+}
+
+func (a *API) handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(openAPIDoc)
+}
+
+// writeServiceError responds with HTTP status matching service error.
+// Unknown errors are logged and hidden from the caller.
+func (a *API) writeServiceError(w http.ResponseWriter, log *slog.Logger, err error) {
+	status := statusFromError(err)
+	if status == http.StatusServiceUnavailable {
+		log.Warn("api request was canceled", sl.Error(err))
+		writeError(w, status, service.ErrRequestCanceled.Error())
+		return
+	}
+	if status == http.StatusInternalServerError {
+		log.Error("failed to handle api request", sl.Error(err))
+		writeError(w, status, "internal error")
+		return
+	}
+
+	// Message of the service error itself is shown without wrapping ops.
+	writeError(w, status, rootError(err).Error())
+}
+
+// statusFromError maps service errors to HTTP statuses.
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPollNotFound),
+		errors.Is(err, service.ErrNoVoteToCancel):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNotPollOwner):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPollFinished),
+		errors.Is(err, service.ErrAlreadyVoted):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrDeadlineInPast),
+		errors.Is(err, service.ErrAnonymousDisabled),
+		errors.Is(err, service.ErrInvalidMaxChoices),
+		errors.Is(err, service.ErrAnonymousVoters),
+		errors.Is(err, service.ErrOnlyOneOptionAllowed),
+		errors.Is(err, service.ErrInvalidOptionNumber),
+		errors.Is(err, service.ErrDuplicateOption),
+		errors.Is(err, service.ErrTooManyOptions),
+		errors.Is(err, service.ErrScoreRequired),
+		errors.Is(err, service.ErrScoreNotAllowed),
+		errors.Is(err, service.ErrInvalidScore),
+		errors.Is(err, service.ErrInvalidReceipt):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrRequestCanceled):
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+// rootError unwraps error chain down to the service error.
+func rootError(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
+
+// pollIDFromPath parses poll ID from the request path.
+func pollIDFromPath(r *http.Request) (uint64, error) {
+	const op = "api.pollIDFromPath"
+
+	pollID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pollID, nil
+}
+
+// pollRequest parses poll ID and channel of requests to a single poll.
+// It responds with an error and returns ok=false in case they are invalid.
+func pollRequest(w http.ResponseWriter, r *http.Request) (pollID uint64, channel string, ok bool) {
+	pollID, err := pollIDFromPath(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid poll id")
+		return 0, "", false
+	}
+
+	channel = r.URL.Query().Get("channel_id")
+	if channel == "" {
+		writeError(w, http.StatusBadRequest, "channel_id query parameter is required")
+		return 0, "", false
+	}
+
+	return pollID, channel, true
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
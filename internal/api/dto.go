@@ -0,0 +1,159 @@
+package api
+
+import (
+	"time"
+	"vote-bot/internal/entity"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type createPollRequest struct {
+	Name      string          `json:"name"`
+	ChannelID string          `json:"channel_id"`
+	Type      entity.PollType `json:"type"`
+	Options   []string        `json:"options"`
+	// ClosesAt is a deadline of the poll, the poll isn't finished automatically in case it's nil.
+	ClosesAt   *time.Time `json:"closes_at"`
+	Anonymous  bool       `json:"anonymous"`
+	MaxChoices uint64     `json:"max_choices"`
+	ShowVoters bool       `json:"show_voters"`
+}
+
+type voteRequest struct {
+	Options []uint64 `json:"options"`
+	// Scores[i] is a score of Options[i], only for score polls.
+	Scores []uint64 `json:"scores,omitempty"`
+}
+
+// receiptRequest is an optional body of vote retraction, receipt is required only in anonymous polls.
+type receiptRequest struct {
+	Receipt string `json:"receipt"`
+}
+
+// receiptResponse is returned for vote in anonymous poll.
+// Receipt is given only to the voter, it's needed to retract the vote.
+type receiptResponse struct {
+	Receipt string `json:"receipt"`
+}
+
+type pollResponse struct {
+	ID          uint64          `json:"id"`
+	Name        string          `json:"name"`
+	Creator     string          `json:"creator"`
+	ChannelID   string          `json:"channel_id"`
+	Type        entity.PollType `json:"type"`
+	IsFinished  bool            `json:"is_finished"`
+	IsAnonymous bool            `json:"is_anonymous"`
+	ClosesAt    *time.Time      `json:"closes_at,omitempty"`
+	MaxChoices  uint64          `json:"max_choices,omitempty"`
+	ShowVoters  bool            `json:"show_voters"`
+	PostID      string          `json:"post_id,omitempty"`
+}
+
+type optionResponse struct {
+	Num  uint64 `json:"num"`
+	Name string `json:"name"`
+}
+
+type pollWithOptionsResponse struct {
+	Poll    pollResponse     `json:"poll"`
+	Options []optionResponse `json:"options"`
+}
+
+type resultsResponse struct {
+	Poll            pollResponse           `json:"poll"`
+	Options         []optionResultResponse `json:"options"`
+	Rounds          []roundResponse        `json:"rounds,omitempty"`
+	Winners         []uint64               `json:"winners"`
+	IsTie           bool                   `json:"is_tie"`
+	TotalVoters     uint64                 `json:"total_voters"`
+	TotalSelections uint64                 `json:"total_selections"`
+}
+
+type optionResultResponse struct {
+	Num          uint64   `json:"num"`
+	Name         string   `json:"name"`
+	Votes        uint64   `json:"votes"`
+	Percent      float64  `json:"percent"`
+	Average      *float64 `json:"average,omitempty"`
+	Distribution []uint64 `json:"distribution,omitempty"`
+	Voters       []string `json:"voters,omitempty"`
+}
+
+type roundResponse struct {
+	Votes      []uint64 `json:"votes"`
+	Eliminated []uint64 `json:"eliminated,omitempty"`
+	Exhausted  uint64   `json:"exhausted"`
+}
+
+func newPollResponse(poll *entity.Poll) pollResponse {
+	resp := pollResponse{
+		ID:          poll.ID,
+		Name:        poll.Name,
+		Creator:     poll.Creator,
+		ChannelID:   poll.Channel,
+		Type:        poll.Type,
+		IsFinished:  poll.IsFinished,
+		IsAnonymous: poll.IsAnonymous,
+		MaxChoices:  poll.MaxChoices,
+		ShowVoters:  poll.ShowVoters,
+		PostID:      poll.PostID,
+	}
+	if !poll.ClosesAt.IsZero() {
+		closesAt := poll.ClosesAt.UTC()
+		resp.ClosesAt = &closesAt
+	}
+
+	return resp
+}
+
+func newOptionsResponse(options []entity.Option) []optionResponse {
+	resp := make([]optionResponse, 0, len(options))
+	for _, opt := range options {
+		resp = append(resp, optionResponse{Num: opt.Num, Name: opt.Name})
+	}
+
+	return resp
+}
+
+func newResultsResponse(results *entity.Results) resultsResponse {
+	resp := resultsResponse{
+		Poll:            newPollResponse(&results.Poll),
+		Options:         make([]optionResultResponse, 0, len(results.Options)),
+		Winners:         results.Winners,
+		IsTie:           results.IsTie(),
+		TotalVoters:     results.TotalVoters,
+		TotalSelections: results.TotalSelections,
+	}
+	if resp.Winners == nil {
+		resp.Winners = []uint64{}
+	}
+
+	for _, opt := range results.Options {
+		optResp := optionResultResponse{
+			Num:          opt.Option.Num,
+			Name:         opt.Option.Name,
+			Votes:        opt.Votes,
+			Percent:      opt.Percent,
+			Distribution: opt.Distribution,
+			Voters:       opt.Voters,
+		}
+		if results.Poll.Type == entity.PollTypeScore {
+			average := opt.Average
+			optResp.Average = &average
+		}
+		resp.Options = append(resp.Options, optResp)
+	}
+
+	for _, round := range results.Rounds {
+		resp.Rounds = append(resp.Rounds, roundResponse{
+			Votes:      round.Votes,
+			Eliminated: round.Eliminated,
+			Exhausted:  round.Exhausted,
+		})
+	}
+
+	return resp
+}
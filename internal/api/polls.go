@@ -0,0 +1,263 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/service"
+	"vote-bot/pkg/sl"
+)
+
+func (a *API) handleCreatePoll(w http.ResponseWriter, r *http.Request, user string) {
+	const op = "api.handleCreatePoll"
+
+	log := a.log.With(slog.String("op", op), slog.String("user_id", user))
+
+	var req createPollRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	switch {
+	case req.Name == "":
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	case req.ChannelID == "":
+		writeError(w, http.StatusBadRequest, "channel_id is required")
+		return
+	case len(req.Options) == 0:
+		writeError(w, http.StatusBadRequest, "poll without options cannot be created")
+		return
+	}
+
+	switch req.Type {
+	case "", entity.PollTypeSingle, entity.PollTypeMulti, entity.PollTypeRanked,
+		entity.PollTypeScore, entity.PollTypeApproval:
+	default:
+		writeError(w, http.StatusBadRequest, "unknown poll type")
+		return
+	}
+
+	poll := entity.Poll{
+		Name:        req.Name,
+		Creator:     user,
+		Channel:     req.ChannelID,
+		Type:        req.Type,
+		IsAnonymous: req.Anonymous,
+		MaxChoices:  req.MaxChoices,
+		ShowVoters:  req.ShowVoters,
+	}
+	if req.ClosesAt != nil {
+		poll.ClosesAt = *req.ClosesAt
+	}
+
+	options := make([]entity.Option, 0, len(req.Options))
+	for _, name := range req.Options {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			writeError(w, http.StatusBadRequest, "option name is required")
+			return
+		}
+		options = append(options, entity.Option{Name: name})
+	}
+
+	newPoll, newOptions, err := a.polls.CreatePoll(r.Context(), poll, options)
+	if err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	a.scheduler.Schedule(*newPoll)
+
+	log.Info("created poll via api", slog.Uint64("poll_id", newPoll.ID))
+
+	// Poll created via API is posted to the channel the same way as the one created in chat.
+	if err := a.notifier.AnnouncePoll(r.Context(), newPoll, newOptions); err != nil {
+		log.Error("failed to announce poll", slog.Uint64("poll_id", newPoll.ID), sl.Error(err))
+	}
+
+	writeJSON(w, http.StatusCreated, pollWithOptionsResponse{
+		Poll:    newPollResponse(newPoll),
+		Options: newOptionsResponse(newOptions),
+	})
+}
+
+func (a *API) handleGetPoll(w http.ResponseWriter, r *http.Request, _ string) {
+	const op = "api.handleGetPoll"
+
+	log := a.log.With(slog.String("op", op))
+
+	pollID, channel, ok := pollRequest(w, r)
+	if !ok {
+		return
+	}
+
+	poll, err := a.polls.GetPoll(r.Context(), pollID, channel)
+	if err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPollResponse(poll))
+}
+
+func (a *API) handleGetOptions(w http.ResponseWriter, r *http.Request, _ string) {
+	const op = "api.handleGetOptions"
+
+	log := a.log.With(slog.String("op", op))
+
+	pollID, channel, ok := pollRequest(w, r)
+	if !ok {
+		return
+	}
+
+	options, err := a.polls.GetOptions(r.Context(), pollID, channel)
+	if err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newOptionsResponse(options))
+}
+
+func (a *API) handleVote(w http.ResponseWriter, r *http.Request, user string) {
+	const op = "api.handleVote"
+
+	log := a.log.With(slog.String("op", op), slog.String("user_id", user))
+
+	pollID, channel, ok := pollRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req voteRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Options) == 0 {
+		writeError(w, http.StatusBadRequest, "no options to vote for")
+		return
+	}
+
+	// Chosen options and receipt aren't logged, since poll may be anonymous.
+	receipt, err := a.votes.Vote(r.Context(), pollID, user, channel, req.Options, req.Scores)
+	if err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	log.Info("user voted via api", slog.Uint64("poll_id", pollID))
+
+	a.notifier.RefreshPollPost(r.Context(), pollID, channel)
+
+	if receipt != "" {
+		writeJSON(w, http.StatusOK, receiptResponse{Receipt: receipt})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleRetractVote(w http.ResponseWriter, r *http.Request, user string) {
+	const op = "api.handleRetractVote"
+
+	log := a.log.With(slog.String("op", op), slog.String("user_id", user))
+
+	pollID, channel, ok := pollRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Body is optional, since receipt is needed only in anonymous polls.
+	var req receiptRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := a.votes.RetractVote(r.Context(), pollID, user, channel, req.Receipt); err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	log.Info("user retracted vote via api", slog.Uint64("poll_id", pollID))
+
+	a.notifier.RefreshPollPost(r.Context(), pollID, channel)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleFinishPoll(w http.ResponseWriter, r *http.Request, user string) {
+	const op = "api.handleFinishPoll"
+
+	log := a.log.With(slog.String("op", op), slog.String("user_id", user))
+
+	pollID, channel, ok := pollRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.polls.FinishPoll(r.Context(), pollID, user, channel); err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	log.Info("poll was finished via api", slog.Uint64("poll_id", pollID))
+
+	a.scheduler.Cancel(pollID)
+	a.notifier.RefreshPollPost(r.Context(), pollID, channel)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleDeletePoll(w http.ResponseWriter, r *http.Request, user string) {
+	const op = "api.handleDeletePoll"
+
+	log := a.log.With(slog.String("op", op), slog.String("user_id", user))
+
+	pollID, channel, ok := pollRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.polls.DeletePoll(r.Context(), pollID, user, channel); err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	log.Info("poll was deleted via api", slog.Uint64("poll_id", pollID))
+
+	a.scheduler.Cancel(pollID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetResults responds with results of the poll.
+// Poll without votes gets zero results instead of an error.
+func (a *API) handleGetResults(w http.ResponseWriter, r *http.Request, _ string) {
+	const op = "api.handleGetResults"
+
+	log := a.log.With(slog.String("op", op))
+
+	pollID, channel, ok := pollRequest(w, r)
+	if !ok {
+		return
+	}
+
+	results, err := a.votes.GetResults(r.Context(), pollID, channel)
+	if errors.Is(err, service.ErrNoVotesInPoll) {
+		results, err = a.votes.GetTally(r.Context(), pollID, channel)
+	}
+	if err != nil {
+		a.writeServiceError(w, log, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newResultsResponse(results))
+}
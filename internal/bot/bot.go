@@ -1,35 +1,110 @@
 package bot
 
 import (
+	"expvar"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"vote-bot/internal/api"
+	"vote-bot/internal/bot/cleanup"
 	"vote-bot/internal/bot/client"
+	"vote-bot/internal/bot/leader"
+	"vote-bot/internal/bot/scheduler"
+	"vote-bot/internal/bot/workers"
 	"vote-bot/internal/config"
-	tarantoolrepo "vote-bot/internal/repo/tarantool"
 	"vote-bot/internal/service"
-
-	"github.com/tarantool/go-tarantool/v2"
 )
 
 type Bot struct {
-	log    *slog.Logger
-	Client *client.Client
+	log       *slog.Logger
+	Client    *client.Client
+	Scheduler *scheduler.Scheduler
+	Cleaner   *cleanup.Cleaner
+	Elector   *leader.Elector
+	Server    *http.Server
 }
 
 // NewBot initializes a new Mattermost bot instance.
-func NewBot(log *slog.Logger, cfg config.Mattermost, conn *tarantool.Connection) (*Bot, error) {
+func NewBot(log *slog.Logger, cfg *config.Config, repo service.Repo) (*Bot, error) {
 	const op = "Bot.New"
 
-	repo := tarantoolrepo.NewRepo(conn)
-	service := service.NewService(repo)
+	service := service.NewService(repo, cfg.Anonymity.Secret)
+
+	scheduler := scheduler.New(log, service.PollService, cfg.Requests.Timeout)
+
+	cleaner := cleanup.New(
+		log, service.CommandService,
+		cfg.Commands.TTL, cfg.Commands.CleanupInterval, cfg.Requests.Timeout,
+	)
+
+	elector, err := leader.New(log, service.LeaseService, cfg.Leader, cfg.Requests.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to initialize leader election: %w", op, err)
+	}
+
+	events := workers.New(log, cfg.Workers.Count, cfg.Workers.QueueSize)
 
-	client, err := client.NewClient(cfg, log, service)
+	client, err := client.NewClient(cfg.Mattermost, cfg.Requests, log, service, scheduler, events)
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to initialize mattermost bot: %w", op, err)
 	}
 
-	return &Bot{
-		log:    log,
-		Client: client,
-	}, nil
+	api, err := api.New(cfg.API, cfg.Requests, log, service.PollService, service.VoteService, scheduler, client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to initialize api: %w", op, err)
+	}
+
+	mux := http.NewServeMux()
+	client.RegisterHandlers(mux)
+	api.RegisterHandlers(mux)
+	// Metrics of event queues among others.
+	mux.Handle("GET /debug/vars", expvar.Handler())
+
+	server := &http.Server{
+		Addr:         cfg.HTTPServer.Address,
+		Handler:      mux,
+		ReadTimeout:  cfg.HTTPServer.Timeout,
+		WriteTimeout: cfg.HTTPServer.Timeout,
+		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
+	}
+
+	bot := &Bot{
+		log:       log,
+		Client:    client,
+		Scheduler: scheduler,
+		Cleaner:   cleaner,
+		Elector:   elector,
+		Server:    server,
+	}
+	mux.HandleFunc("GET "+HealthPath, bot.handleHealth)
+
+	return bot, nil
+}
+
+// Start runs leader election. Only the leader listens to websocket,
+// finishes polls by deadline and cleans up processed commands,
+// so that commands aren't executed by every replica.
+// Slash commands, buttons and API are served by all replicas.
+func (b *Bot) Start() {
+	b.Elector.Start(b.lead, b.standBy)
+}
+
+// Stop stops handling of events and steps down, so that another replica takes over.
+// Posts already queued are handled before leadership is released.
+func (b *Bot) Stop() {
+	b.standBy()
+	b.Client.Shutdown()
+	b.Elector.Stop()
+}
+
+func (b *Bot) lead() {
+	b.Client.StartListening()
+	b.Scheduler.Start(b.Client.NotifyPollFinished)
+	b.Cleaner.Start()
+}
+
+func (b *Bot) standBy() {
+	b.Client.StopListening()
+	b.Scheduler.Stop()
+	b.Cleaner.Stop()
 }
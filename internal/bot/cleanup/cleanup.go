@@ -0,0 +1,114 @@
+package cleanup
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+	"vote-bot/pkg/sl"
+)
+
+type CommandService interface {
+	DeleteExpired(ctx context.Context, ttl time.Duration) (uint64, error)
+}
+
+// Cleaner periodically deletes processed commands older than TTL.
+type Cleaner struct {
+	log      *slog.Logger
+	commands CommandService
+	ttl      time.Duration
+	interval time.Duration
+	// timeout limits one cleanup.
+	timeout time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(log *slog.Logger, commands CommandService, ttl, interval, timeout time.Duration) *Cleaner {
+	return &Cleaner{
+		log:      log,
+		commands: commands,
+		ttl:      ttl,
+		interval: interval,
+		timeout:  timeout,
+	}
+}
+
+// Start runs cleanup right away and then every interval until Stop is called.
+func (c *Cleaner) Start() {
+	const op = "bot.cleanup.Start"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+
+	go c.run(ctx, c.done)
+
+	c.log.Info(
+		"cleanup of processed commands started",
+		slog.String("op", op),
+		slog.Duration("ttl", c.ttl),
+		slog.Duration("interval", c.interval),
+	)
+}
+
+// Stop interrupts cleanup in progress and waits until it's stopped.
+func (c *Cleaner) Stop() {
+	const op = "bot.cleanup.Stop"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cancel == nil {
+		return
+	}
+
+	c.cancel()
+	<-c.done
+	c.cancel = nil
+
+	c.log.Info("cleanup of processed commands stopped", slog.String("op", op))
+}
+
+func (c *Cleaner) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.cleanup(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Cleaner) cleanup(ctx context.Context) {
+	const op = "bot.cleanup.cleanup"
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	deleted, err := c.commands.DeleteExpired(ctx, c.ttl)
+	if err != nil {
+		c.log.Error("failed to delete expired commands", slog.String("op", op), sl.Error(err))
+		return
+	}
+
+	if deleted != 0 {
+		c.log.Info("deleted expired commands", slog.String("op", op), slog.Uint64("count", deleted))
+	}
+}
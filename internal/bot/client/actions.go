@@ -0,0 +1,205 @@
+package client
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/service"
+	"vote-bot/pkg/sl"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// VoteActionPath is a path of the endpoint that handles clicks on vote buttons.
+const VoteActionPath = "/actions/vote"
+
+// Keys of the context that Mattermost sends back on button click.
+const (
+	actionCtxToken  = "token"
+	actionCtxPollID = "poll_id"
+	actionCtxOption = "option"
+	// actionCtxSelected is added by Mattermost when option of select menu is chosen.
+	actionCtxSelected = "selected_option"
+)
+
+// ActionsEnabled reports whether poll posts get vote buttons.
+func (c *Client) ActionsEnabled() bool {
+	return c.config.ActionsURL != "" && c.config.ActionsToken != ""
+}
+
+// pollAttachments forms attachment with one vote button per option
+// showing the current number of votes. Options of score polls get
+// select menus with scores instead of buttons.
+//
+// Finished polls don't have buttons. Anonymous polls don't have them either,
+// since buttons change the previous choice, which isn't linked to voter there.
+func (c *Client) pollAttachments(results *entity.Results) []*model.SlackAttachment {
+	poll := &results.Poll
+	if !c.ActionsEnabled() || poll.IsFinished || poll.IsAnonymous {
+		return nil
+	}
+
+	url := strings.TrimSuffix(c.config.ActionsURL, "/") + VoteActionPath
+
+	actions := make([]*model.PostAction, 0, len(results.Options))
+	for _, opt := range results.Options {
+		action := &model.PostAction{
+			Id:    fmt.Sprintf("vote%d", opt.Option.Num),
+			Type:  model.PostActionTypeButton,
+			Name:  fmt.Sprintf("%d) %s (%d)", opt.Option.Num, opt.Option.Name, opt.Votes),
+			Style: "default",
+			Integration: &model.PostActionIntegration{
+				URL: url,
+				Context: map[string]any{
+					actionCtxToken:  c.config.ActionsToken,
+					actionCtxPollID: poll.ID,
+					actionCtxOption: opt.Option.Num,
+				},
+			},
+		}
+
+		if poll.Type == entity.PollTypeScore {
+			action.Type = model.PostActionTypeSelect
+			action.Name = fmt.Sprintf("%d) %s (%.2f avg)", opt.Option.Num, opt.Option.Name, opt.Average)
+			for score := 0; score <= entity.MaxScore; score++ {
+				action.Options = append(action.Options, &model.PostActionOptions{
+					Text:  strconv.Itoa(score),
+					Value: strconv.Itoa(score),
+				})
+			}
+		}
+
+		actions = append(actions, action)
+	}
+
+	var text string
+	switch poll.Type {
+	case entity.PollTypeMulti:
+		text = "Click options to choose or unchoose them."
+	case entity.PollTypeRanked:
+		text = "Click options in order of preference, click again to unrank."
+	case entity.PollTypeScore:
+		text = fmt.Sprintf("Rate options from 0 to %d.", entity.MaxScore)
+	case entity.PollTypeApproval:
+		text = "Click options to approve or disapprove them."
+	default:
+		text = "Click an option to vote for it."
+	}
+
+	return []*model.SlackAttachment{{
+		Text:    text,
+		Actions: actions,
+	}}
+}
+
+// HandleVoteAction serves clicks on vote buttons of poll posts.
+//
+// It votes for the clicked option on behalf of the clicking user
+// and updates the post with the new number of votes.
+func (c *Client) HandleVoteAction(w http.ResponseWriter, r *http.Request) {
+	const op = "bot.client.HandleVoteAction"
+
+	log := c.l.With(slog.String("op", op))
+
+	var req model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("failed to decode action request", sl.Error(err))
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	token, _ := req.Context[actionCtxToken].(string)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.config.ActionsToken)) != 1 {
+		log.Warn("got action with invalid token", slog.String("post_id", req.PostId))
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	// Numbers in context are decoded from JSON as float64.
+	pollIDFloat, ok1 := req.Context[actionCtxPollID].(float64)
+	optFloat, ok2 := req.Context[actionCtxOption].(float64)
+	if !ok1 || !ok2 {
+		log.Error("invalid action context", slog.String("post_id", req.PostId))
+		http.Error(w, "invalid context", http.StatusBadRequest)
+		return
+	}
+	pollID, opt := uint64(pollIDFloat), uint64(optFloat)
+
+	// Clicked option isn't logged together with user, since poll may be anonymous.
+	log = log.With(
+		slog.Uint64("poll_id", pollID),
+		slog.String("user_id", req.UserId),
+		slog.String("username", c.username(req.UserId)),
+	)
+
+	ctx, cancel := c.requestContext(r.Context())
+	defer cancel()
+
+	resp := &model.PostActionIntegrationResponse{
+		EphemeralText: "your vote was counted",
+	}
+
+	// Select menus of score polls send chosen score, buttons send nothing.
+	var err error
+	if selected, ok := req.Context[actionCtxSelected].(string); ok {
+		var score uint64
+		score, err = strconv.ParseUint(selected, 10, 64)
+		if err == nil {
+			err = c.service.VoteService.RateOption(ctx, pollID, req.UserId, req.ChannelId, opt, score)
+		}
+	} else {
+		var isChosen bool
+		isChosen, err = c.service.VoteService.ToggleOption(ctx, pollID, req.UserId, req.ChannelId, opt)
+		if !isChosen {
+			resp.EphemeralText = "your vote for this option was removed"
+		}
+	}
+	if err != nil {
+		var text string
+		switch {
+		case errors.Is(err, service.ErrPollNotFound):
+			text = "poll not found"
+		case errors.Is(err, service.ErrPollFinished):
+			text = "failed to vote because poll was finished"
+		case errors.Is(err, service.ErrInvalidOptionNumber):
+			text = "invalid option number"
+		case errors.Is(err, service.ErrTooManyOptions):
+			text = "too many options are chosen, unchoose one of them first"
+		case errors.Is(err, service.ErrInvalidScore), errors.Is(err, strconv.ErrSyntax):
+			text = "invalid score"
+		case errors.Is(err, service.ErrAnonymousChoice):
+			text = fmt.Sprintf("anonymous poll is voted with `%s%s`, buttons can't be used there", commandPrefix, cmdVote)
+		case errors.Is(err, service.ErrRequestCanceled):
+			text = "request took too long and was canceled, please try again"
+		default:
+			text = "failed to vote"
+		}
+
+		log.Error("failed to vote with action", sl.Error(err))
+		writeJSON(w, &model.PostActionIntegrationResponse{EphemeralText: text})
+		return
+	}
+
+	// Show new number of votes on the buttons.
+	results, err := c.service.VoteService.GetTally(ctx, pollID, req.ChannelId)
+	if err != nil {
+		log.Error("failed to get poll tally", sl.Error(err))
+	} else {
+		update := &model.Post{
+			Id:      req.PostId,
+			Message: formatPoll(results),
+		}
+		model.ParseSlackAttachment(update, c.pollAttachments(results))
+		resp.Update = update
+	}
+
+	log.Info("user voted with action")
+
+	writeJSON(w, resp)
+}
@@ -1,42 +1,128 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"sync"
 	"time"
 	"vote-bot/internal/config"
+	"vote-bot/internal/entity"
 	"vote-bot/internal/service"
 	"vote-bot/pkg/sl"
 
-	"github.com/gorilla/websocket"
 	"github.com/mattermost/mattermost-server/v6/model"
 )
 
+// Scheduler finishes polls by their deadline.
+type Scheduler interface {
+	Schedule(poll entity.Poll)
+	Cancel(pollID uint64)
+}
+
+// EventQueue handles websocket events concurrently.
+// Events with the same key are handled in order they were submitted.
+type EventQueue interface {
+	Start()
+	Submit(key string, task func()) bool
+	Stop()
+}
+
 type Client struct {
-	config  config.Mattermost
-	l       *slog.Logger
-	service *service.Service
-
-	mattermostClient          *model.Client4
-	mattermostWebSocketClient *model.WebSocketClient
-	mattermostUser            *model.User
-	mattermostTeam            *model.Team
+	config    config.Mattermost
+	l         *slog.Logger
+	service   *service.Service
+	scheduler Scheduler
+	events    EventQueue
+	// timeout limits handling of one command or button click.
+	timeout time.Duration
+
+	// done is closed on StopListening to close websocket and stop reconnecting.
+	// listenerDone is closed when listener goroutine exits.
+	done         chan struct{}
+	listenerDone chan struct{}
+	listenMu     sync.Mutex
+	startEvents  sync.Once
+	// ctx is canceled on Shutdown to interrupt commands in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// websocketURL and tlsConfig are used to (re)connect to websocket.
+	websocketURL string
+	tlsConfig    *tls.Config
+	health       connHealth
+
+	// recentPosts are IDs of the last submitted posts.
+	recentPosts *recentPosts
+	// users resolves user IDs to names.
+	users *userDirectory
+	// cursors caches high-water marks of handled posts by channel.
+	cursors   map[string]*entity.Cursor
+	cursorsMu sync.Mutex
+
+	mattermostClient *model.Client4
+	mattermostUser   *model.User
+	mattermostTeam   *model.Team
 }
 
-func NewClient(cfg config.Mattermost, logger *slog.Logger, service *service.Service) (*Client, error) {
+func NewClient(
+	cfg config.Mattermost,
+	requests config.Requests,
+	logger *slog.Logger,
+	service *service.Service,
+	scheduler Scheduler,
+	events EventQueue,
+) (*Client, error) {
 	const op = "bot.client.NewClient"
 
 	client := &Client{
-		config:  cfg,
-		l:       logger,
-		service: service,
+		config:    cfg,
+		l:         logger,
+		service:   service,
+		scheduler: scheduler,
+		events:    events,
+		timeout:   requests.Timeout,
+
+		recentPosts: newRecentPosts(recentPostsLimit),
+		cursors:     make(map[string]*entity.Cursor),
+	}
+	client.users = newUserDirectory(cfg.UsersCacheTTL, func(ids []string) ([]*model.User, error) {
+		users, _, err := client.mattermostClient.GetUsersByIds(ids)
+		return users, err
+	})
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+	client.health.set(ConnStateDisabled, nil)
+	if cfg.ListenWebSocket {
+		client.health.set(ConnStateDisconnected, nil)
 	}
 
 	log := client.l.With(slog.String("op", op))
 
+	wsURL, err := websocketURL(cfg.Server)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	client.websocketURL = wsURL
+
+	client.tlsConfig, err = newTLSConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if cfg.InsecureSkipVerify {
+		log.Warn("tls certificate of mattermost server isn't verified")
+	}
+
 	// Create a new mattermost client
 	client.mattermostClient = model.NewAPIv4Client(cfg.Server.String())
+	client.mattermostClient.HTTPClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: client.tlsConfig,
+		},
+	}
 
 	// Login
 	client.mattermostClient.SetToken(cfg.Token)
@@ -62,46 +148,125 @@ func NewClient(cfg config.Mattermost, logger *slog.Logger, service *service.Serv
 	return client, nil
 }
 
-// ListenToEvents connects to the Mattermost WebSocket API and listens for user events (messages).
-func (c *Client) ListenToEvents() {
-	const op = "bot.client.ListenToEvents"
-
-	log := c.l.With(slog.String("op", op))
-
-	var err error
-	failCount := 0
-	for {
-		log.Debug("Establishing weebsocket connection to mattermost API...")
-		c.mattermostWebSocketClient, err = model.NewWebSocketClient4WithDialer(
-			&websocket.Dialer{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-			fmt.Sprintf("ws://%s", c.config.Server.Host+c.config.Server.Path),
-			c.mattermostClient.AuthToken,
-		)
-		if err != nil {
-			log.Warn("Mattermost websocket disconnected, retrying", sl.Error(err))
-			failCount += 1
-			if failCount == 5 {
-				time.Sleep(10 * time.Second)
-			}
-			continue
-		}
-		log.Debug("Established connection")
-		log.Info("Mattermost websocket connected")
-
-		c.mattermostWebSocketClient.Listen()
-
-		for event := range c.mattermostWebSocketClient.EventChannel {
-			c.handleEvent(event)
-		}
+// submitEvent queues post from the event to be handled after previous posts of its channel.
+// Updates of users are applied to the user directory right away.
+func (c *Client) submitEvent(log *slog.Logger, event *model.WebSocketEvent) {
+	switch event.EventType() {
+	case model.WebsocketEventPosted:
+	case model.WebsocketEventUserUpdated:
+		c.handleUserUpdated(log, event)
+		return
+	default:
+		log.Debug("skipping event", slog.String("type", event.EventType()))
+		return
+	}
+
+	data, _ := event.GetData()["post"].(string)
+
+	post := &model.Post{}
+	if err := json.Unmarshal([]byte(data), post); err != nil {
+		log.Error("failed to unmarshal post", sl.Error(err))
+		return
+	}
+
+	c.submitPost(log, post)
+}
+
+// submitPost queues post to be handled after previous posts of its channel.
+// It blocks while queue of the channel is full.
+//
+// Posts already submitted are skipped, since replayed posts may be received via websocket too.
+// It must be called only from the listener goroutine.
+func (c *Client) submitPost(log *slog.Logger, post *model.Post) bool {
+	// ignore messages sent by bot itself.
+	if post.UserId == c.mattermostUser.Id {
+		return false
+	}
+
+	if !c.recentPosts.add(post.Id) {
+		log.Debug("skipping duplicate post", slog.String("post_id", post.Id))
+		return false
+	}
+
+	// Commands of one channel are handled in order, so they are keyed by channel.
+	// Poll belongs to a single channel, hence votes in it are ordered too.
+	task := func() {
+		c.handlePost(post)
+		c.advanceCursor(post)
+	}
+	if !c.events.Submit(post.ChannelId, task) {
+		log.Warn("event queue is stopped, post is dropped", slog.String("channel_id", post.ChannelId))
+		return false
+	}
+
+	return true
+}
+
+// StartListening starts listening to websocket in background in case it's enabled.
+func (c *Client) StartListening() {
+	if !c.config.ListenWebSocket {
+		return
+	}
+
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+
+	if c.listenerDone != nil {
+		return
 	}
+
+	c.startEvents.Do(c.events.Start)
+
+	c.done = make(chan struct{})
+	c.listenerDone = make(chan struct{})
+	go func(listenerDone chan struct{}) {
+		defer close(listenerDone)
+		c.listenToEvents()
+	}(c.listenerDone)
 }
 
+// StopListening closes websocket and waits until listener is stopped.
+// Posts already queued are still handled.
 func (c *Client) StopListening() {
-	const op = "bot.client.StopListening"
+	c.listenMu.Lock()
+	defer c.listenMu.Unlock()
+
+	if c.listenerDone == nil {
+		return
+	}
+
+	close(c.done)
+	<-c.listenerDone
+	c.listenerDone = nil
+}
+
+// Shutdown stops listening and waits until queued posts are handled.
+// Commands still running after that are interrupted.
+func (c *Client) Shutdown() {
+	defer c.cancel()
 
-	c.l.Info("closing web socket connection...", slog.String("op", op))
+	c.StopListening()
+	c.events.Stop()
+}
+
+// stopped reports whether StopListening was called.
+func (c *Client) stopped() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestContext returns context of handling one request.
+// It's done when parent is done, the client is stopped or the timeout expires.
+func (c *Client) requestContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(parent, c.timeout)
+	stop := context.AfterFunc(c.ctx, cancel)
 
-	c.mattermostWebSocketClient.Close()
+	return ctx, func() {
+		stop()
+		cancel()
+	}
 }
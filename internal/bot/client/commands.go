@@ -1,11 +1,305 @@
 package client
 
+import (
+	"fmt"
+	"strings"
+	"vote-bot/internal/bot/parser"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/export"
+)
+
+// Names of bot commands, in chat they are prefixed with commandPrefix.
+const (
+	commandPrefix = "!"
+
+	cmdCreatePoll         = "create_poll"
+	cmdCreateMultiPoll    = "create_multipoll"
+	cmdCreateRankedPoll   = "create_ranked_poll"
+	cmdCreateScorePoll    = "create_score_poll"
+	cmdCreateApprovalPoll = "create_approval_poll"
+	cmdFinishPoll         = "finish_poll"
+	cmdDeletePoll         = "delete_poll"
+	cmdVote               = "vote"
+	cmdRetractVote        = "retract_vote"
+	cmdGetResults         = "get_results"
+	cmdPollInfo           = "poll_info"
+	cmdListPolls          = "list_polls"
+	cmdMyPolls            = "my_polls"
+	cmdMyVote             = "my_vote"
+	cmdExport             = "export"
+	cmdVoters             = "voters"
+	cmdRenamePoll         = "rename_poll"
+	cmdAddOption          = "add_option"
+	cmdRenameOption       = "rename_option"
+	cmdRemoveOption       = "remove_option"
+	cmdMoveOption         = "move_option"
+	cmdPollHistory        = "poll_history"
+	cmdReopenPoll         = "reopen_poll"
+	cmdHelp               = "help"
+)
+
 var (
-	cmdCreatePoll      = "!create_poll"
-	cmdCreateMultiPoll = "!create_multipoll"
-	cmdFinishPoll      = "!finish_poll"
-	cmdDeletePoll      = "!delete_poll"
-	cmdVote            = "!vote"
-	cmdRetractVote     = "!retract_vote"
-	cmdGetResults      = "!get_results"
+	argPollID = parser.Arg{Name: "poll_id", Kind: parser.KindUint, Usage: "ID of the poll"}
+
+	argChoice = parser.Arg{
+		Name: "choice",
+		Kind: parser.KindString,
+		Usage: "numbers of the chosen options, either on the same line or on the next one. " +
+			"In score polls options are given with scores, e.g. 1:5 2:3",
+		Variadic: true,
+	}
+
+	argPollName = parser.Arg{
+		Name:  "name",
+		Kind:  parser.KindText,
+		Usage: "name of the poll, quote it in case options are given on the same line",
+	}
+	argPollOptions = parser.Arg{
+		Name:     "option",
+		Kind:     parser.KindString,
+		Usage:    "options to choose from, either after the name or one per line below it",
+		Variadic: true,
+		PerLine:  true,
+	}
+
+	argOption   = parser.Arg{Name: "option", Kind: parser.KindUint, Usage: "number of the option"}
+	argPosition = parser.Arg{Name: "position", Kind: parser.KindUint, Usage: "new number of the option"}
+	argNewName  = parser.Arg{Name: "name", Kind: parser.KindText, Usage: "new name, quotes aren't needed"}
+	argStatus   = parser.Arg{
+		Name:     "status",
+		Kind:     parser.KindString,
+		Usage:    "which polls to list, open ones by default",
+		Optional: true,
+		Values:   []string{string(entity.PollStatusOpen), string(entity.PollStatusFinished), string(entity.PollStatusAll)},
+	}
+	argFormat = parser.Arg{
+		Name:     "format",
+		Kind:     parser.KindString,
+		Usage:    "format of the file, csv by default",
+		Optional: true,
+		Values:   exportFormats(),
+	}
+	argCommand = parser.Arg{
+		Name:     "command",
+		Kind:     parser.KindString,
+		Usage:    "name of the command, with or without " + commandPrefix,
+		Optional: true,
+	}
+	argReceipt = parser.Arg{
+		Name:     "receipt",
+		Kind:     parser.KindString,
+		Usage:    "receipt you got when voting in anonymous poll, it isn't needed in other polls",
+		Optional: true,
+	}
+	argDeadline = parser.Arg{
+		Name:     "deadline",
+		Kind:     parser.KindDeadline,
+		Usage:    "new deadline, either duration like 2h30m or UTC time like 2006-01-02T15:04, the previous one is kept if it hasn't passed",
+		Optional: true,
+	}
+
+	flagAnonymous = parser.Flag{
+		Name:  "anonymous",
+		Kind:  parser.KindBool,
+		Usage: "don't store who voted for what",
+	}
+	flagCloses = parser.Flag{
+		Name:  "closes",
+		Kind:  parser.KindDeadline,
+		Value: "deadline",
+		Usage: "finish the poll automatically, deadline is either duration like 2h30m or UTC time like 2006-01-02T15:04",
+	}
+	flagShowVoters = parser.Flag{
+		Name:  "show-voters",
+		Kind:  parser.KindBool,
+		Usage: "list voters of each option in results, not allowed for anonymous polls",
+	}
+	flagBefore = parser.Flag{
+		Name:  "before",
+		Kind:  parser.KindUint,
+		Value: "poll_id",
+		Usage: "show polls older than the poll, it's used to get the next page",
+	}
+	flagMax = parser.Flag{
+		Name:  "max",
+		Kind:  parser.KindUint,
+		Value: "number",
+		Usage: "max number of options in one vote",
+	}
 )
+
+// createPollCommand returns spec of command creating poll of the type.
+func createPollCommand(name string, summary string, flags ...parser.Flag) parser.Command {
+	return parser.Command{
+		Name:    name,
+		Summary: summary,
+		Args:    []parser.Arg{argPollName, argPollOptions},
+		Flags:   append(flags, flagAnonymous, flagShowVoters, flagCloses),
+		Examples: []string{
+			fmt.Sprintf("%s%s --closes 2h Where to go?\nCinema\nTheatre", commandPrefix, name),
+			fmt.Sprintf("%s%s \"Where to go?\" Cinema Theatre", commandPrefix, name),
+		},
+	}
+}
+
+// commands is a registry of bot commands. It's used both to parse commands and to form their help.
+var commands = parser.NewRegistry(commandPrefix,
+	createPollCommand(cmdCreatePoll, "Creates a poll, one option is chosen in a vote."),
+	createPollCommand(cmdCreateMultiPoll, "Creates a poll, several options may be chosen in a vote."),
+	createPollCommand(
+		cmdCreateRankedPoll,
+		"Creates a ranked poll, options are listed in a vote in order of preference. "+
+			"Results are counted with instant-runoff.",
+	),
+	createPollCommand(
+		cmdCreateScorePoll,
+		fmt.Sprintf("Creates a score poll, options are scored from 0 to %d in a vote.", entity.MaxScore),
+	),
+	createPollCommand(
+		cmdCreateApprovalPoll,
+		"Creates an approval poll, all suitable options are chosen in a vote.",
+		flagMax,
+	),
+	parser.Command{
+		Name: cmdVote,
+		Summary: "Votes in the poll, vote may be changed until the poll is finished. " +
+			"Vote in anonymous poll can't be changed, you get a receipt to retract it instead.",
+		Args:     []parser.Arg{argPollID, argChoice},
+		Examples: []string{"!vote 5 1", "!vote 5\n3 1 2", "!vote 5 1:5 2:3"},
+	},
+	parser.Command{
+		Name:     cmdRetractVote,
+		Summary:  "Retracts your vote in the poll.",
+		Args:     []parser.Arg{argPollID, argReceipt},
+		Examples: []string{"!retract_vote 5", "!retract_vote 5 RECEIPT"},
+	},
+	parser.Command{
+		Name:     cmdGetResults,
+		Summary:  "Shows results of the poll.",
+		Args:     []parser.Arg{argPollID},
+		Examples: []string{"!get_results 5"},
+	},
+	parser.Command{
+		Name:     cmdPollInfo,
+		Summary:  "Shows definition of the poll: its creator, type, status, deadline, options and number of voters.",
+		Args:     []parser.Arg{argPollID},
+		Examples: []string{"!poll_info 5"},
+	},
+	parser.Command{
+		Name:     cmdListPolls,
+		Summary:  "Lists polls of the channel from the newest to the oldest.",
+		Args:     []parser.Arg{argStatus},
+		Flags:    []parser.Flag{flagBefore},
+		Examples: []string{"!list_polls", "!list_polls all --before 20"},
+	},
+	parser.Command{
+		Name:     cmdMyPolls,
+		Summary:  "Lists polls you created in all channels from the newest to the oldest.",
+		Flags:    []parser.Flag{flagBefore},
+		Examples: []string{"!my_polls", "!my_polls --before 20"},
+	},
+	parser.Command{
+		Name:     cmdMyVote,
+		Summary:  "Shows options you chose in the poll, it's seen only by you.",
+		Args:     []parser.Arg{argPollID},
+		Examples: []string{"!my_vote 5"},
+	},
+	parser.Command{
+		Name:     cmdVoters,
+		Summary:  "Lists who voted for each option of the poll, anonymous polls are refused.",
+		Args:     []parser.Arg{argPollID},
+		Examples: []string{"!voters 5"},
+	},
+	parser.Command{
+		Name: cmdExport,
+		Summary: "Exports results of the poll to a file attached to the response. " +
+			"Choices of each voter are exported too, unless the poll is anonymous.",
+		Args:     []parser.Arg{argPollID, argFormat},
+		Examples: []string{"!export 5", "!export 5 md"},
+	},
+	parser.Command{
+		Name:     cmdRenamePoll,
+		Summary:  "Renames the poll, only its creator can do it until the poll is finished.",
+		Args:     []parser.Arg{argPollID, argNewName},
+		Examples: []string{"!rename_poll 5 Where to go on Friday?"},
+	},
+	parser.Command{
+		Name:     cmdAddOption,
+		Summary:  "Adds an option to the end of the poll, only its creator can do it until the poll is finished.",
+		Args:     []parser.Arg{argPollID, {Name: "name", Kind: parser.KindText, Usage: "name of the option, quotes aren't needed"}},
+		Examples: []string{"!add_option 5 Bowling"},
+	},
+	parser.Command{
+		Name:     cmdRenameOption,
+		Summary:  "Renames an option of the poll keeping votes for it, only the poll creator can do it until the poll is finished.",
+		Args:     []parser.Arg{argPollID, argOption, argNewName},
+		Examples: []string{"!rename_option 5 2 Theatre"},
+	},
+	parser.Command{
+		Name: cmdRemoveOption,
+		Summary: "Removes an option of the poll, only its creator can do it until the poll is finished. " +
+			"The option is removed from votes and their voters are notified, options after it move one number up.",
+		Args:     []parser.Arg{argPollID, argOption},
+		Examples: []string{"!remove_option 5 2"},
+	},
+	parser.Command{
+		Name:     cmdMoveOption,
+		Summary:  "Moves an option of the poll to another position keeping votes, only the poll creator can do it until the poll is finished.",
+		Args:     []parser.Arg{argPollID, argOption, argPosition},
+		Examples: []string{"!move_option 5 3 1"},
+	},
+	parser.Command{
+		Name:     cmdPollHistory,
+		Summary:  "Shows edits of the poll made after it was created.",
+		Args:     []parser.Arg{argPollID},
+		Examples: []string{"!poll_history 5"},
+	},
+	parser.Command{
+		Name:     cmdFinishPoll,
+		Summary:  "Finishes the poll, only its creator can do it.",
+		Args:     []parser.Arg{argPollID},
+		Examples: []string{"!finish_poll 5"},
+	},
+	parser.Command{
+		Name: cmdReopenPoll,
+		Summary: "Reopens the finished poll, only its creator can do it. " +
+			"Results posted when the poll was finished are marked as superseded.",
+		Args:     []parser.Arg{argPollID, argDeadline},
+		Examples: []string{"!reopen_poll 5", "!reopen_poll 5 24h"},
+	},
+	parser.Command{
+		Name:     cmdDeletePoll,
+		Summary:  "Deletes the poll with all its votes, only its creator can do it.",
+		Args:     []parser.Arg{argPollID},
+		Examples: []string{"!delete_poll 5"},
+	},
+	parser.Command{
+		Name:     cmdHelp,
+		Summary:  "Shows list of commands or help of the command.",
+		Args:     []parser.Arg{argCommand},
+		Examples: []string{"!help", "!help create_poll"},
+	},
+)
+
+// exportFormats returns names of formats polls are exported to.
+func exportFormats() []string {
+	formats := make([]string, 0, len(export.Formats))
+	for _, format := range export.Formats {
+		formats = append(formats, string(format))
+	}
+
+	return formats
+}
+
+// formatHelp forms list of all commands with their synopses.
+func formatHelp() string {
+	var b strings.Builder
+
+	b.WriteString("#### Commands\n")
+	for _, cmd := range commands.Commands() {
+		b.WriteString(fmt.Sprintf("- `%s` %s\n", commands.Synopsis(cmd), cmd.Summary))
+	}
+	b.WriteString(fmt.Sprintf("\nSee `%s%s <command>` for arguments, flags and examples of the command.", commandPrefix, cmdHelp))
+
+	return b.String()
+}
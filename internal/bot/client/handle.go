@@ -1,354 +1,1162 @@
 package client
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
+	"time"
+	"vote-bot/internal/bot/parser"
 	"vote-bot/internal/entity"
+	"vote-bot/internal/export"
 	"vote-bot/internal/service"
 	"vote-bot/pkg/sl"
 
 	"github.com/mattermost/mattermost-server/v6/model"
 )
 
-func (c *Client) handleEvent(event *model.WebSocketEvent) {
+// commandRequest is a command invocation that doesn't depend on the way
+// it was delivered to the bot (websocket event or HTTP request).
+type commandRequest struct {
+	// cmd is command name without prefix, e.g. "vote".
+	cmd string
+	// args are parsed arguments and flags of the command.
+	args *parser.Call
+	// err is an error of parsing the command, it's shown to the user with command help.
+	err error
+
+	userID    string
+	channelID string
+	// postID is ID of the post with command, it's empty for slash commands.
+	postID string
+}
+
+// commandResponse is a result of command execution.
+type commandResponse struct {
+	text string
+	// public responses are posted to the channel for everyone.
+	// The other ones are shown only to the caller where it's possible
+	// (thread reply for posts, ephemeral message for slash commands).
+	public bool
+	// ephemeral responses are seen only by the caller even for commands in posts,
+	// e.g. choice of the user. They aren't saved, so they aren't sent again for duplicate posts.
+	ephemeral bool
+	// secret responses are ephemeral ones which are never sent to direct channel,
+	// since Mattermost stores direct messages, e.g. receipt of anonymous vote.
+	secret bool
+	// attachments are added to the response post, e.g. vote buttons.
+	attachments []*model.SlackAttachment
+	// fileIDs are IDs of files uploaded to the channel which are attached to the response post.
+	fileIDs []string
+}
+
+func reply(text string) commandResponse {
+	return commandResponse{text: text}
+}
+
+func announce(text string) commandResponse {
+	return commandResponse{text: text, public: true}
+}
+
+func whisper(text string) commandResponse {
+	return commandResponse{text: text, ephemeral: true}
+}
+
+func confide(text string) commandResponse {
+	return commandResponse{text: text, ephemeral: true, secret: true}
+}
+
+// failed forms private response to the command failed with unexpected error.
+// Commands which were canceled or timed out get a distinct text.
+func failed(text string, err error) commandResponse {
+	if errors.Is(err, service.ErrRequestCanceled) {
+		return reply("request took too long and was canceled, please try again")
+	}
+
+	return reply(text)
+}
+
+// handlePost executes command from the post received via websocket or replayed after reconnect.
+func (c *Client) handlePost(post *model.Post) {
 	const op = "bot.client.handle"
 
 	log := c.l.With(
 		slog.String("op", op),
 	)
 
-	log.Debug(
-		"got new event",
-		slog.String("type", event.EventType()),
-	)
-
-	// ignore event in case it's not post.
-	if event.EventType() != model.WebsocketEventPosted {
+	req, ok := parseCommand(post.Message)
+	if !ok {
 		return
 	}
+	req.userID = post.UserId
+	req.channelID = post.ChannelId
+	req.postID = post.Id
 
-	post := &model.Post{}
+	// Message text isn't logged, since it may contain choice in anonymous poll.
+	log.Info(
+		"got new command",
+		slog.String("cmd", req.cmd),
+		slog.String("channel_id", post.ChannelId),
+		slog.String("user_id", post.UserId),
+		slog.String("username", c.username(post.UserId)),
+		slog.String("message_id", post.Id),
+	)
+
+	ctx, cancel := c.requestContext(c.ctx)
+	defer cancel()
 
-	log.Debug("unmarshalling post...")
-	err := json.Unmarshal([]byte(event.GetData()["post"].(string)), &post)
+	// The same post may be delivered twice, e.g. after reconnect or to several bot replicas.
+	processed, claimed, err := c.service.CommandService.Claim(ctx, post.Id, post.ChannelId)
 	if err != nil {
-		log.Error("failed to unmarshal post")
+		log.Error("failed to claim command", slog.String("message_id", post.Id), sl.Error(err))
+		c.sendMessage(post.ChannelId, failed("failed to handle command", err).text, post.Id)
 		return
 	}
+	if !claimed {
+		c.handleDuplicate(log, post, processed)
+		return
+	}
+
+	resp := c.executeCommand(ctx, req)
+
+	if resp.ephemeral {
+		c.sendWhisper(post.ChannelId, post.UserId, resp, post.Id)
+		resp = commandResponse{}
+	}
+
+	c.respond(log, post, entity.ProcessedCommand{
+		PostID:      post.Id,
+		Channel:     post.ChannelId,
+		ProcessedAt: time.Now(),
+		Response:    resp.text,
+		Public:      resp.public,
+	}, resp)
+}
 
-	// ignore messages sent by bot itself.
-	if post.UserId == c.mattermostUser.Id {
+// respond saves response to the command and sends it.
+// Response is saved first, so that it could be sent again in case sending fails.
+// Outcome is saved with its own context, since the command may use up the timeout.
+//
+// Attachments and files of resp are added to the response post, they aren't saved.
+func (c *Client) respond(log *slog.Logger, post *model.Post, cmd entity.ProcessedCommand, resp commandResponse) {
+	ctx, cancel := c.requestContext(c.ctx)
+	defer cancel()
+
+	if cmd.Response != "" {
+		if err := c.service.CommandService.Complete(ctx, cmd); err != nil {
+			log.Error("failed to save command response", slog.String("message_id", post.Id), sl.Error(err))
+		}
+
+		replyTo := post.Id
+		if cmd.Public {
+			replyTo = ""
+		}
+		reply := &model.Post{ChannelId: post.ChannelId, Message: cmd.Response, RootId: replyTo, FileIds: resp.fileIDs}
+		if len(resp.attachments) != 0 {
+			model.ParseSlackAttachment(reply, resp.attachments)
+		}
+		if err := c.createPost(reply); err != nil {
+			return
+		}
+	}
+
+	if err := c.service.CommandService.MarkSent(ctx, cmd); err != nil {
+		log.Error("failed to save command outcome", slog.String("message_id", post.Id), sl.Error(err))
+	}
+}
+
+// handleDuplicate handles post with the command which was claimed before.
+// Saved response is sent in case it wasn't sent the first time,
+// commands still processing (or interrupted) aren't executed again.
+func (c *Client) handleDuplicate(log *slog.Logger, post *model.Post, processed *entity.ProcessedCommand) {
+	log = log.With(slog.String("message_id", post.Id), slog.String("status", string(processed.Status)))
+
+	if processed.Status != entity.CommandStatusDone {
+		log.Info("skipping command which was already handled")
 		return
 	}
 
-	msg := post.Message
-	log.Info(
-		"got new message",
-		slog.String("msg", msg),
-		slog.String("channel_id", post.ChannelId),
-		slog.String("user_id", post.UserId),
-		slog.String("message_id", post.Id),
+	log.Info("sending saved response to command which was already handled")
+
+	c.respond(log, post, *processed, commandResponse{})
+}
+
+// parseCommand parses message with command.
+// ok is false for messages which are not commands, invalid commands are returned with err.
+func parseCommand(msg string) (req commandRequest, ok bool) {
+	call, err := commands.Parse(msg)
+	if errors.Is(err, parser.ErrNotCommand) {
+		return commandRequest{}, false
+	}
+	if err != nil {
+		var parseErr *parser.Error
+		if errors.As(err, &parseErr) {
+			req.cmd = parseErr.Command
+		}
+		req.err = err
+		return req, true
+	}
+
+	return commandRequest{cmd: call.Command, args: call}, true
+}
+
+// invalidCommand forms response to the command which failed to parse, with help of the command.
+func invalidCommand(req commandRequest) commandResponse {
+	cmd, ok := commands.Lookup(req.cmd)
+	if !ok {
+		return reply(fmt.Sprintf("%s, see `%s%s` for the list of commands", req.err, commandPrefix, cmdHelp))
+	}
+
+	return reply(fmt.Sprintf("%s\n\n%s", req.err, commands.Usage(cmd)))
+}
+
+// executeCommand runs the command and forms response for the caller.
+func (c *Client) executeCommand(ctx context.Context, req commandRequest) commandResponse {
+	const op = "bot.client.executeCommand"
+
+	log := c.l.With(
+		slog.String("op", op),
+		slog.String("cmd", req.cmd),
 	)
 
-	// skip non-command messages.
-	if msg[0] != '!' {
-		return
+	if req.err != nil {
+		log.Info("invalid command", sl.Error(req.err))
+		return invalidCommand(req)
 	}
 
-	// Process command
-	lines := strings.Split(strings.TrimSpace(msg), "\n")
-	sep := strings.Index(lines[0], " ")
-	if sep == -1 {
-		c.sendMessage(post.ChannelId, "invalid command", post.Id)
-		return
+	switch req.cmd {
+	case cmdCreatePoll:
+		return c.createPoll(ctx, log, req, entity.PollTypeSingle)
+	case cmdCreateMultiPoll:
+		return c.createPoll(ctx, log, req, entity.PollTypeMulti)
+	case cmdCreateRankedPoll:
+		return c.createPoll(ctx, log, req, entity.PollTypeRanked)
+	case cmdCreateScorePoll:
+		return c.createPoll(ctx, log, req, entity.PollTypeScore)
+	case cmdCreateApprovalPoll:
+		return c.createPoll(ctx, log, req, entity.PollTypeApproval)
+	case cmdFinishPoll:
+		return c.finishPoll(ctx, log, req)
+	case cmdDeletePoll:
+		return c.deletePoll(ctx, log, req)
+	case cmdVote:
+		return c.vote(ctx, log, req)
+	case cmdRetractVote:
+		return c.retractVote(ctx, log, req)
+	case cmdGetResults:
+		return c.getResults(ctx, log, req)
+	case cmdPollInfo:
+		return c.pollInfo(ctx, log, req)
+	case cmdListPolls:
+		return c.listPolls(ctx, log, req)
+	case cmdMyPolls:
+		return c.myPolls(ctx, log, req)
+	case cmdMyVote:
+		return c.myVote(ctx, log, req)
+	case cmdExport:
+		return c.exportPoll(ctx, log, req)
+	case cmdVoters:
+		return c.voters(ctx, log, req)
+	case cmdRenamePoll:
+		return c.renamePoll(ctx, log, req)
+	case cmdAddOption:
+		return c.addOption(ctx, log, req)
+	case cmdRenameOption:
+		return c.renameOption(ctx, log, req)
+	case cmdRemoveOption:
+		return c.removeOption(ctx, log, req)
+	case cmdMoveOption:
+		return c.moveOption(ctx, log, req)
+	case cmdPollHistory:
+		return c.pollHistory(ctx, log, req)
+	case cmdReopenPoll:
+		return c.reopenPoll(ctx, log, req)
+	case cmdHelp:
+		return help(req)
+	}
+
+	return reply(fmt.Sprintf("unknown command %s%s, see `%s%s` for the list of commands", commandPrefix, req.cmd, commandPrefix, cmdHelp))
+}
+
+func (c *Client) createPoll(
+	ctx context.Context,
+	log *slog.Logger,
+	req commandRequest,
+	pollType entity.PollType,
+) commandResponse {
+	prefix := pollPrefix(pollType)
+
+	poll := entity.Poll{
+		Name:        req.args.String(argPollName.Name),
+		Creator:     req.userID,
+		Channel:     req.channelID,
+		Type:        pollType,
+		ClosesAt:    req.args.Time(flagCloses.Name),
+		IsAnonymous: req.args.Bool(flagAnonymous.Name),
+		MaxChoices:  req.args.Uint(flagMax.Name),
+		ShowVoters:  req.args.Bool(flagShowVoters.Name),
 	}
-	args := strings.Split(lines[0], " ")
-	cmd, arg := args[0], strings.Join(args[1:], " ")
 
-	switch cmd {
-	case cmdCreatePoll, cmdCreateMultiPoll:
-		var prefix string
-		if cmd == cmdCreateMultiPoll {
-			prefix = "multi"
+	names := req.args.Strings(argPollOptions.Name)
+	options := make([]entity.Option, 0, len(names))
+	for _, name := range names {
+		options = append(options, entity.Option{Name: name})
+	}
+
+	// Create (multi)poll with options
+	newPoll, newOptions, err := c.service.PollService.CreatePoll(ctx, poll, options)
+	if err != nil {
+		if errors.Is(err, service.ErrDeadlineInPast) {
+			log.Error("poll deadline is in the past", slog.Time("closes_at", poll.ClosesAt))
+			return reply("poll deadline is in the past")
 		}
 
-		poll := entity.Poll{
-			Name:        arg,
-			Creator:     post.UserId,
-			Channel:     post.ChannelId,
-			IsMultiVote: false,
+		if errors.Is(err, service.ErrAnonymousDisabled) {
+			log.Error("anonymous polls are disabled")
+			return reply("anonymous polls are disabled on this server")
 		}
 
-		if cmd == cmdCreateMultiPoll {
-			poll.IsMultiVote = true
+		if errors.Is(err, service.ErrInvalidMaxChoices) {
+			log.Error("max number of choices exceeds number of options", slog.Uint64("max_choices", poll.MaxChoices))
+			return reply("max number of choices exceeds number of options")
 		}
 
-		// Skip if no options specified
-		if len(lines) <= 1 {
-			log.Error(fmt.Sprintf("failed to create %spoll without options", prefix))
-			c.sendMessage(post.ChannelId, fmt.Sprintf("%spoll without options cannot be created", prefix), post.Id)
-			return
+		if errors.Is(err, service.ErrAnonymousVoters) {
+			log.Error("anonymous poll can't show voters")
+			return reply(fmt.Sprintf("voters of anonymous poll can't be shown, use either --%s or --%s", flagAnonymous.Name, flagShowVoters.Name))
 		}
 
-		options := make([]entity.Option, 0, len(lines)-1)
-		for _, line := range lines[1:] {
-			options = append(options, entity.Option{Name: strings.TrimSpace(line)})
+		log.Error(fmt.Sprintf("failed to create %spoll", prefix), sl.Error(err))
+		return failed(fmt.Sprintf("failed to create %spoll", prefix), err)
+	}
+
+	c.scheduler.Schedule(*newPoll)
+
+	log.Info(fmt.Sprintf("created %spoll", prefix), slog.Uint64("poll_id", newPoll.ID), slog.Any("options", options))
+
+	// Post the poll itself, this post is updated with every vote.
+	if err := c.AnnouncePoll(ctx, newPoll, newOptions); err != nil {
+		log.Error(fmt.Sprintf("failed to announce %spoll", prefix), slog.Uint64("poll_id", newPoll.ID), sl.Error(err))
+		return announce(formatPoll(emptyResults(newPoll, newOptions)))
+	}
+
+	return commandResponse{}
+}
+
+func (c *Client) finishPoll(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	err := c.service.PollService.FinishPoll(ctx, pollID, req.userID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		// Create (multi)poll with options
-		newPoll, newOptions, err := c.service.PollService.CreatePoll(poll, options)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to create %spoll", prefix), sl.Error(err))
-			c.sendMessage(post.ChannelId, fmt.Sprintf("failed to create %spoll", prefix), post.Id)
-			return
+		if errors.Is(err, service.ErrNotPollOwner) {
+			log.Error("failed to finish the poll: user is not the poll creator", slog.Uint64("pollID", pollID))
+			return reply("impossible to finish poll which you are not creator of")
 		}
 
-		// Form response
-		var b strings.Builder
-		b.WriteString(fmt.Sprintf("New %spoll created: %s\nID: %d\n", prefix, newPoll.Name, newPoll.ID))
-		for _, opt := range newOptions {
-			b.WriteString(fmt.Sprintf("%d) %s\n", opt.Num, opt.Name))
+		if errors.Is(err, service.ErrPollFinished) {
+			log.Error("poll is already finished", slog.Uint64("pollID", pollID))
+			return reply("poll is already finished")
 		}
 
-		// Send response to channel
-		c.sendMessage(post.ChannelId, b.String(), "")
+		log.Error("failed to finish poll", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to finish poll", err)
+	}
 
-		log.Info(fmt.Sprintf("created %spoll", prefix), slog.Any("poll", poll), slog.Any("options", options))
+	log.Info("poll was finished", slog.Uint64("poll_id", pollID))
 
-	case cmdFinishPoll:
-		pollID, err := pollIDFromString(args[1])
-		if err != nil {
-			log.Error("invalid poll ID", sl.Error(err))
-			c.sendMessage(post.ChannelId, "invalid poll ID", post.Id)
-			return
+	c.scheduler.Cancel(pollID)
+
+	// Poll post shows that poll is finished, so there's no need in one more message.
+	if c.RefreshPollPost(ctx, pollID, req.channelID) {
+		return reply(fmt.Sprintf("poll %d was finished", pollID))
+	}
+
+	return announce(fmt.Sprintf("poll %d was finished", pollID))
+}
+
+// reopenPoll reopens the finished poll on behalf of its creator and marks its results as superseded.
+func (c *Client) reopenPoll(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	reopening, err := c.service.EditService.ReopenPoll(ctx, pollID, req.userID, req.channelID, req.args.Time(argDeadline.Name))
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		err = c.service.PollService.FinishPoll(pollID, post.UserId, post.ChannelId)
-		if err != nil {
-			if errors.Is(err, service.ErrPollNotFound) {
-				log.Error("poll not found", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "poll not found", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrNotPollOwner) {
+			log.Error("failed to reopen the poll: user is not the poll creator", slog.Uint64("pollID", pollID))
+			return reply("impossible to reopen poll which you are not creator of")
+		}
 
-			if errors.Is(err, service.ErrNotPollOwner) {
-				log.Error("failed to finish the poll: user is not the poll creator", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "impossible to finish poll which you are not creator of", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrPollNotFinished) {
+			log.Error("poll isn't finished", slog.Uint64("pollID", pollID))
+			return reply("poll isn't finished")
+		}
 
-			log.Error("failed to finish poll", slog.Uint64("pollID", pollID), sl.Error(err))
-			c.sendMessage(post.ChannelId, "failed to finish poll", post.Id)
-			return
+		if errors.Is(err, service.ErrDeadlineInPast) {
+			log.Error("failed to reopen the poll: deadline is in the past", slog.Uint64("pollID", pollID))
+			return reply("deadline must be in the future")
 		}
 
-		c.sendMessage(post.ChannelId, fmt.Sprintf("poll %d was finished", pollID), "")
+		log.Error("failed to reopen poll", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to reopen poll", err)
+	}
 
-		log.Info("poll was finished", slog.Uint64("poll_id", pollID))
+	log.Info("poll was reopened", slog.Uint64("poll_id", pollID))
 
-	case cmdDeletePoll:
-		pollID, err := pollIDFromString(args[1])
-		if err != nil {
-			log.Error("invalid poll ID", sl.Error(err))
-			c.sendMessage(post.ChannelId, "invalid poll ID", post.Id)
-			return
+	c.scheduler.Schedule(reopening.Poll)
+	c.supersedeResults(log, reopening)
+
+	text := fmt.Sprintf("poll %d was reopened", pollID)
+	if closesAt := reopening.Poll.ClosesAt; !closesAt.IsZero() {
+		text += fmt.Sprintf(", it closes at %s", closesAt.UTC().Format(deadlineLayout))
+	}
+
+	return c.edited(ctx, pollID, req.channelID, text)
+}
+
+func (c *Client) deletePoll(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	err := c.service.PollService.DeletePoll(ctx, pollID, req.userID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		err = c.service.PollService.DeletePoll(pollID, post.UserId, post.ChannelId)
-		if err != nil {
-			if errors.Is(err, service.ErrPollNotFound) {
-				log.Error("poll not found", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "poll not found", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrNotPollOwner) {
+			log.Error("failed to delete the poll: user is not the poll creator", slog.Uint64("pollID", pollID))
+			return reply("impossible to delete poll which you are not creator of")
+		}
 
-			if errors.Is(err, service.ErrNotPollOwner) {
-				log.Error("failed to delete the poll: user is not the poll creator", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "impossible to delete poll which you are not creator of", post.Id)
-				return
-			}
+		log.Error("failed to delete poll", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to delete poll", err)
+	}
 
-			log.Error("failed to delete poll", slog.Uint64("pollID", pollID), sl.Error(err))
-			c.sendMessage(post.ChannelId, "failed to delete poll", post.Id)
-			return
+	log.Info("poll was deleted", slog.Uint64("poll_id", pollID))
+
+	c.scheduler.Cancel(pollID)
+
+	return announce(fmt.Sprintf("poll %d was deleted", pollID))
+}
+
+func (c *Client) vote(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	c.deleteAnonymousCommand(ctx, req, pollID)
+
+	opts, scores, err := choiceFromString(strings.Join(req.args.Strings(argChoice.Name), " "))
+	if err != nil {
+		log.Error("invalid option nums", sl.Error(err))
+		return reply("invalid options")
+	}
+
+	receipt, err := c.service.VoteService.Vote(ctx, pollID, req.userID, req.channelID, opts, scores)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		c.sendMessage(post.ChannelId, fmt.Sprintf("poll %d was deleted", pollID), "")
+		if errors.Is(err, service.ErrPollFinished) {
+			log.Error("failed to vote because poll was finished", slog.Uint64("pollID", pollID))
+			return reply("failed to vote because poll was finished")
+		}
 
-		log.Info("poll was deleted", slog.Uint64("poll_id", pollID))
+		if errors.Is(err, service.ErrOnlyOneOptionAllowed) {
+			log.Error("failed to vote because it doesn't support multiple options", slog.Uint64("pollID", pollID))
+			return reply("failed to vote because it doesn't support multiple options")
+		}
 
-	case cmdVote:
-		pollID, err := pollIDFromString(args[1])
-		if err != nil {
-			log.Error("invalid poll ID", sl.Error(err))
-			c.sendMessage(post.ChannelId, "invalid poll ID", post.Id)
-			return
+		if errors.Is(err, service.ErrInvalidOptionNumber) {
+			log.Error("invalid option number", slog.Uint64("pollID", pollID))
+			return reply("invalid option number")
 		}
 
-		opts, err := optsFromString(lines[1])
-		if err != nil {
-			log.Error("invalid option nums", slog.Any("options", opts), sl.Error(err))
-			c.sendMessage(post.ChannelId, "invalid options", post.Id)
-			return
+		if errors.Is(err, service.ErrDuplicateOption) {
+			log.Error("option is chosen more than once", slog.Uint64("pollID", pollID))
+			return reply("option is chosen more than once")
 		}
 
-		err = c.service.VoteService.Vote(pollID, post.UserId, post.ChannelId, opts)
-		if err != nil {
-			if errors.Is(err, service.ErrPollNotFound) {
-				log.Error("poll not found", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "poll not found", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrTooManyOptions) {
+			log.Error("too many options are chosen", slog.Uint64("pollID", pollID))
+			return reply("too many options are chosen")
+		}
 
-			if errors.Is(err, service.ErrPollFinished) {
-				log.Error("failed to vote because poll was finished", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "failed to vote because poll was finished", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrScoreRequired) {
+			log.Error("options are chosen without scores", slog.Uint64("pollID", pollID))
+			return reply(fmt.Sprintf("give every option a score from 0 to %d, e.g. 1:5 2:3", entity.MaxScore))
+		}
 
-			if errors.Is(err, service.ErrOnlyOneOptionAllowed) {
-				log.Error("failed to vote because it doesn't support multiple options", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "failed to vote because it doesn't support multiple options", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrScoreNotAllowed) {
+			log.Error("options are scored in poll without scores", slog.Uint64("pollID", pollID))
+			return reply("options can be scored only in score polls")
+		}
 
-			if errors.Is(err, service.ErrInvalidOptionNumber) {
-				log.Error("invalid option number", slog.Uint64("pollID", pollID), slog.Any("options", opts))
-				c.sendMessage(post.ChannelId, "invalid option number", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrInvalidScore) {
+			log.Error("invalid score", slog.Uint64("pollID", pollID))
+			return reply(fmt.Sprintf("score must be from 0 to %d", entity.MaxScore))
+		}
 
-			log.Error("failed to vote", slog.Uint64("pollID", pollID), slog.Any("options", opts), sl.Error(err))
-			c.sendMessage(post.ChannelId, "failed to vote", post.Id)
-			return
+		if errors.Is(err, service.ErrAlreadyVoted) {
+			log.Info("user has already voted in anonymous poll", slog.Uint64("pollID", pollID))
+			return whisper(fmt.Sprintf(
+				"you have already voted in this anonymous poll, retract your vote with the receipt to vote again: `%s%s %d RECEIPT`",
+				commandPrefix, cmdRetractVote, pollID,
+			))
 		}
 
-		c.sendMessage(post.ChannelId, "your vote was counted", "")
-		log.Info("user voted", slog.Uint64("poll_id", pollID), slog.String("userId", post.UserId))
+		log.Error("failed to vote", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to vote", err)
+	}
 
-	case cmdRetractVote:
-		pollID, err := pollIDFromString(args[1])
-		if err != nil {
-			log.Error("invalid poll ID", sl.Error(err))
-			c.sendMessage(post.ChannelId, "invalid poll ID", post.Id)
-			return
+	log.Info("user voted", slog.Uint64("poll_id", pollID), slog.String("userId", req.userID))
+
+	c.RefreshPollPost(ctx, pollID, req.channelID)
+
+	// Receipt is the only link between the user and the ballot, so it's neither logged nor saved.
+	if receipt != "" {
+		return confide(fmt.Sprintf(
+			"your vote was counted. Nobody else can see your choice, keep this command to retract the vote: `%s%s %d %s`",
+			commandPrefix, cmdRetractVote, pollID, receipt,
+		))
+	}
+
+	return whisper("your vote was counted")
+}
+
+func (c *Client) retractVote(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	c.deleteAnonymousCommand(ctx, req, pollID)
+
+	err := c.service.VoteService.RetractVote(ctx, pollID, req.userID, req.channelID, req.args.String(argReceipt.Name))
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		err = c.service.VoteService.RetractVote(pollID, post.UserId, post.ChannelId)
-		if err != nil {
-			if errors.Is(err, service.ErrPollNotFound) {
-				log.Error("poll not found", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "poll not found", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrPollFinished) {
+			log.Error("poll was finished", slog.Uint64("pollID", pollID))
+			return reply("poll was finished")
+		}
 
-			if errors.Is(err, service.ErrPollFinished) {
-				log.Error("poll was finished", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "poll was finished", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrNoVoteToCancel) {
+			log.Error("no vote to cancel", slog.Uint64("pollID", pollID))
+			return reply("you haven't vote yet in this poll")
+		}
 
-			if errors.Is(err, service.ErrNoVoteToCancel) {
-				log.Error("no vote to cancel", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "you haven't vote yet in this poll", post.Id)
-				return
-			}
+		if errors.Is(err, service.ErrInvalidReceipt) {
+			log.Info("invalid receipt of anonymous vote", slog.Uint64("pollID", pollID))
+			return whisper(fmt.Sprintf(
+				"vote in anonymous poll is retracted with the receipt you got when voting: `%s%s %d RECEIPT`",
+				commandPrefix, cmdRetractVote, pollID,
+			))
+		}
 
-			log.Error("failed to retract vote", slog.Uint64("pollID", pollID), sl.Error(err))
-			c.sendMessage(post.ChannelId, "failed to retract vote", post.Id)
-			return
+		log.Error("failed to retract vote", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to retract vote", err)
+	}
+
+	log.Info("user retracted vote", slog.Uint64("poll_id", pollID), slog.String("userId", req.userID))
+
+	c.RefreshPollPost(ctx, pollID, req.channelID)
+
+	return whisper("your vote was retracted")
+}
+
+func (c *Client) getResults(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	results, err := c.service.VoteService.GetResults(ctx, pollID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		c.sendMessage(post.ChannelId, "your vote was retracted", "")
-		log.Info("user retracted vote", slog.Uint64("poll_id", pollID), slog.String("userId", post.UserId))
+		if errors.Is(err, service.ErrNoVotesInPoll) {
+			log.Error("no votes in poll", slog.Uint64("pollID", pollID))
+			return reply("no votes in poll")
+		}
 
-	case cmdGetResults:
-		pollID, err := pollIDFromString(args[1])
-		if err != nil {
-			log.Error("invalid poll ID", sl.Error(err))
-			c.sendMessage(post.ChannelId, "invalid poll ID", post.Id)
-			return
+		log.Error("failed to get poll results", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed get poll results", err)
+	}
+
+	text := c.resultsText(results)
+
+	log.Info("counted poll results", slog.Uint64("poll_id", pollID), slog.String("userId", req.userID))
+
+	// Results are shown in the poll post, so they are sent only to the caller.
+	if c.RefreshPollPost(ctx, pollID, req.channelID) {
+		return reply(text)
+	}
+
+	return announce(text)
+}
+
+func (c *Client) pollInfo(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	info, err := c.service.VoteService.GetPollInfo(ctx, pollID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		results, err := c.service.VoteService.GetResults(pollID, post.ChannelId)
-		if err != nil {
-			if errors.Is(err, service.ErrPollNotFound) {
-				log.Error("poll not found", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "poll not found", "")
-				return
-			}
+		log.Error("failed to get poll info", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to get poll info", err)
+	}
 
-			if errors.Is(err, service.ErrNoVotesInPoll) {
-				log.Error("no votes in poll", slog.Uint64("pollID", pollID))
-				c.sendMessage(post.ChannelId, "no votes in poll", "")
-				return
-			}
+	log.Info("got poll info", slog.Uint64("poll_id", pollID), slog.String("userId", req.userID))
 
-			log.Error("failed to get poll results", slog.Uint64("pollID", pollID), sl.Error(err))
-			c.sendMessage(post.ChannelId, "failed get poll results", "")
-			return
+	var reopener string
+	if info.Poll.ReopenedBy != "" {
+		reopener = c.displayName(info.Poll.ReopenedBy)
+	}
+
+	return reply(formatPollInfo(info, c.displayName(info.Poll.Creator), reopener))
+}
+
+// pollsPageSize is a number of polls in one response of list commands.
+const pollsPageSize = 10
+
+func (c *Client) listPolls(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	status := entity.PollStatus(req.args.String(argStatus.Name))
+	if status == "" {
+		status = entity.PollStatusOpen
+	}
+	beforeID := req.args.Uint(flagBefore.Name)
+
+	page, err := c.service.PollService.ListChannelPolls(ctx, req.channelID, status, beforeID, pollsPageSize)
+	if err != nil {
+		log.Error("failed to list polls", slog.String("status", string(status)), sl.Error(err))
+		return failed("failed to list polls", err)
+	}
+
+	log.Info("listed polls", slog.String("status", string(status)), slog.Int("count", len(page.Polls)))
+
+	if len(page.Polls) == 0 {
+		if beforeID != 0 {
+			return reply("no more polls")
+		}
+		if status == entity.PollStatusAll {
+			return reply("no polls in the channel yet")
+		}
+		return reply(fmt.Sprintf("no %s polls in the channel", status))
+	}
+
+	title := fmt.Sprintf("Polls of the channel (%s)", status)
+	next := fmt.Sprintf("%s%s %s --%s %d", commandPrefix, cmdListPolls, status, flagBefore.Name, page.NextBefore)
+
+	return reply(formatPollPage(title, page, nil, next))
+}
+
+func (c *Client) myPolls(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	beforeID := req.args.Uint(flagBefore.Name)
+
+	page, err := c.service.PollService.ListUserPolls(ctx, req.userID, beforeID, pollsPageSize)
+	if err != nil {
+		log.Error("failed to list user polls", slog.String("userId", req.userID), sl.Error(err))
+		return failed("failed to list your polls", err)
+	}
+
+	log.Info("listed user polls", slog.String("userId", req.userID), slog.Int("count", len(page.Polls)))
+
+	if len(page.Polls) == 0 {
+		if beforeID != 0 {
+			return reply("no more polls")
+		}
+		return reply("you haven't created any polls yet")
+	}
+
+	// Polls may be created in different channels, and commands work only in the channel of the poll.
+	channels := make(map[string]string)
+	for _, summary := range page.Polls {
+		if _, ok := channels[summary.Poll.Channel]; !ok {
+			channels[summary.Poll.Channel] = c.channelName(summary.Poll.Channel)
+		}
+	}
+
+	next := fmt.Sprintf("%s%s --%s %d", commandPrefix, cmdMyPolls, flagBefore.Name, page.NextBefore)
+
+	return reply(formatPollPage("Your polls", page, channels, next))
+}
+
+func (c *Client) myVote(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	vote, err := c.service.VoteService.GetUserVote(ctx, pollID, req.userID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return whisper("poll not found")
+		}
+
+		if errors.Is(err, service.ErrNotVoted) {
+			log.Info("user hasn't voted", slog.Uint64("pollID", pollID), slog.String("userId", req.userID))
+			return whisper("you haven't voted in this poll")
+		}
+
+		if errors.Is(err, service.ErrAnonymousChoice) {
+			log.Info("vote in anonymous poll was requested", slog.Uint64("pollID", pollID))
+			return whisper("poll is anonymous, choices in it aren't linked to voters")
+		}
+
+		log.Error("failed to get user vote", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to get your vote", err)
+	}
+
+	log.Info("got user vote", slog.Uint64("poll_id", pollID), slog.String("userId", req.userID))
+
+	return whisper(formatUserVote(vote))
+}
+
+func (c *Client) voters(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	results, err := c.service.VoteService.GetVoters(ctx, pollID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
+		}
+
+		if errors.Is(err, service.ErrAnonymousVoters) {
+			log.Info("voters of anonymous poll were requested", slog.Uint64("pollID", pollID))
+			return reply("poll is anonymous, its voters can't be shown")
+		}
+
+		if errors.Is(err, service.ErrNoVotesInPoll) {
+			log.Error("no votes in poll", slog.Uint64("pollID", pollID))
+			return reply("no votes in poll")
+		}
+
+		log.Error("failed to get poll voters", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to get poll voters", err)
+	}
+
+	log.Info("listed poll voters", slog.Uint64("poll_id", pollID), slog.String("userId", req.userID))
+
+	poll := &results.Poll
+	text := fmt.Sprintf("#### Voters of %spoll %d: %s\n", pollPrefix(poll.Type), poll.ID, poll.Name)
+
+	return reply(text + formatVoters(results, c.voterNames(results)))
+}
+
+func (c *Client) exportPoll(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+	format := export.Format(req.args.String(argFormat.Name))
+	if format == "" {
+		format = export.Formats[0]
+	}
+
+	poll, err := c.service.VoteService.GetExport(ctx, pollID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
 		}
 
-		var b strings.Builder // response
-		b.WriteString("Results:\n")
-		for key, val := range results {
-			b.WriteString(fmt.Sprintf("%s: %d\n", key, val))
+		if errors.Is(err, service.ErrNoVotesInPoll) {
+			log.Error("no votes in poll", slog.Uint64("pollID", pollID))
+			return reply("no votes in poll")
 		}
 
-		c.sendMessage(post.ChannelId, b.String(), "")
-		log.Info("counted poll results", slog.Uint64("poll_id", pollID), slog.String("userId", post.UserId))
+		log.Error("failed to get poll export", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to export poll", err)
+	}
+
+	voters := make([]string, 0, len(poll.Votes))
+	for _, vote := range poll.Votes {
+		voters = append(voters, vote.User)
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, export.New(poll, c.usernames(voters))); err != nil {
+		log.Error("failed to write poll export", slog.Uint64("pollID", pollID), sl.Error(err))
+		return reply("failed to export poll")
+	}
+
+	upload, _, err := c.mattermostClient.UploadFile(buf.Bytes(), req.channelID, export.FileName(&poll.Results.Poll, format))
+	if err != nil {
+		log.Error("failed to upload poll export", slog.Uint64("pollID", pollID), sl.Error(err))
+		return reply("failed to upload exported file")
 	}
+
+	fileIDs := make([]string, 0, len(upload.FileInfos))
+	for _, info := range upload.FileInfos {
+		fileIDs = append(fileIDs, info.Id)
+	}
+
+	log.Info("exported poll", slog.Uint64("poll_id", pollID), slog.String("format", string(format)))
+
+	return commandResponse{
+		text:    fmt.Sprintf("Results of poll %d: %s", pollID, poll.Results.Poll.Name),
+		fileIDs: fileIDs,
+	}
+}
+
+// renamePoll renames the poll on behalf of its creator.
+func (c *Client) renamePoll(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	err := c.service.EditService.RenamePoll(ctx, pollID, req.userID, req.channelID, req.args.String(argNewName.Name))
+	if err != nil {
+		return editFailed(log, pollID, "failed to rename poll", err)
+	}
+
+	log.Info("poll was renamed", slog.Uint64("poll_id", pollID))
+
+	return c.edited(ctx, pollID, req.channelID, fmt.Sprintf("poll %d was renamed", pollID))
+}
+
+// addOption adds option to the end of the poll on behalf of its creator.
+func (c *Client) addOption(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	option, err := c.service.EditService.AddOption(ctx, pollID, req.userID, req.channelID, req.args.String(argNewName.Name))
+	if err != nil {
+		return editFailed(log, pollID, "failed to add option", err)
+	}
+
+	log.Info("option was added", slog.Uint64("poll_id", pollID), slog.Uint64("option", option.Num))
+
+	return c.edited(ctx, pollID, req.channelID, fmt.Sprintf("option %d) %s was added to poll %d", option.Num, option.Name, pollID))
+}
+
+// renameOption renames option of the poll keeping votes for it.
+func (c *Client) renameOption(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+	num := req.args.Uint(argOption.Name)
+
+	err := c.service.EditService.RenameOption(ctx, pollID, req.userID, req.channelID, num, req.args.String(argNewName.Name))
+	if err != nil {
+		return editFailed(log, pollID, "failed to rename option", err)
+	}
+
+	log.Info("option was renamed", slog.Uint64("poll_id", pollID), slog.Uint64("option", num))
+
+	return c.edited(ctx, pollID, req.channelID, fmt.Sprintf("option %d of poll %d was renamed", num, pollID))
+}
+
+// removeOption removes option of the poll and notifies voters whose votes lost it.
+func (c *Client) removeOption(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+	num := req.args.Uint(argOption.Name)
+
+	removal, err := c.service.EditService.RemoveOption(ctx, pollID, req.userID, req.channelID, num)
+	if err != nil {
+		return editFailed(log, pollID, "failed to remove option", err)
+	}
+
+	log.Info("option was removed", slog.Uint64("poll_id", pollID), slog.Uint64("option", num), slog.Int("changed_votes", len(removal.Votes)))
+
+	c.notifyRemoval(ctx, log, pollID, req.channelID, removal)
+
+	return c.edited(ctx, pollID, req.channelID, fmt.Sprintf("option %d) %s was removed from poll %d", num, removal.Option.Name, pollID))
 }
 
-func (c *Client) sendMessage(channel, message, replyToID string) {
-	const op = "bot.client.sendMessage"
+// moveOption moves option of the poll to another position keeping votes for it.
+func (c *Client) moveOption(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+	num := req.args.Uint(argOption.Name)
+	position := req.args.Uint(argPosition.Name)
+
+	err := c.service.EditService.MoveOption(ctx, pollID, req.userID, req.channelID, num, position)
+	if err != nil {
+		return editFailed(log, pollID, "failed to move option", err)
+	}
+
+	log.Info("option was moved", slog.Uint64("poll_id", pollID), slog.Uint64("option", num), slog.Uint64("position", position))
+
+	return c.edited(ctx, pollID, req.channelID, fmt.Sprintf("option %d of poll %d was moved to position %d", num, pollID, position))
+}
+
+// pollHistory lists edits of the poll with their editors.
+func (c *Client) pollHistory(ctx context.Context, log *slog.Logger, req commandRequest) commandResponse {
+	pollID := req.args.Uint(argPollID.Name)
+
+	edits, err := c.service.EditService.GetPollEdits(ctx, pollID, req.channelID)
+	if err != nil {
+		if errors.Is(err, service.ErrPollNotFound) {
+			log.Error("poll not found", slog.Uint64("pollID", pollID))
+			return reply("poll not found")
+		}
+
+		log.Error("failed to get poll history", slog.Uint64("pollID", pollID), sl.Error(err))
+		return failed("failed to get poll history", err)
+	}
+
+	log.Info("got poll history", slog.Uint64("poll_id", pollID), slog.Int("edits", len(edits)))
+
+	if len(edits) == 0 {
+		return reply(fmt.Sprintf("poll %d wasn't edited", pollID))
+	}
+
+	ids := make([]string, 0, len(edits))
+	for _, edit := range edits {
+		ids = append(ids, edit.Editor)
+	}
+
+	return reply(formatPollEdits(pollID, edits, c.usernames(ids)))
+}
+
+// editFailed forms response to failed edit of the poll.
+func editFailed(log *slog.Logger, pollID uint64, text string, err error) commandResponse {
+	switch {
+	case errors.Is(err, service.ErrPollNotFound):
+		log.Error("poll not found", slog.Uint64("pollID", pollID))
+		return reply("poll not found")
+	case errors.Is(err, service.ErrNotPollOwner):
+		log.Error(text+": user is not the poll creator", slog.Uint64("pollID", pollID))
+		return reply("only creator of the poll can edit it")
+	case errors.Is(err, service.ErrPollFinished):
+		log.Error(text+": poll is finished", slog.Uint64("pollID", pollID))
+		return reply("poll is finished and can't be edited")
+	case errors.Is(err, service.ErrEmptyName):
+		log.Error(text+": name is empty", slog.Uint64("pollID", pollID))
+		return reply("name can't be empty")
+	case errors.Is(err, service.ErrOptionNotFound):
+		log.Error(text+": option not found", slog.Uint64("pollID", pollID))
+		return reply("option not found")
+	case errors.Is(err, service.ErrLastOption):
+		log.Error(text+": option is the last one", slog.Uint64("pollID", pollID))
+		return reply("the only option of the poll can't be removed")
+	case errors.Is(err, service.ErrInvalidPosition):
+		log.Error(text+": invalid position", slog.Uint64("pollID", pollID))
+		return reply("invalid position, it must be between 1 and the number of options")
+	}
+
+	log.Error(text, slog.Uint64("pollID", pollID), sl.Error(err))
+	return failed(text, err)
+}
+
+// edited refreshes the poll post after the edit. The channel is told
+// about the edit only if there's no post showing it.
+func (c *Client) edited(ctx context.Context, pollID uint64, channel string, text string) commandResponse {
+	if c.RefreshPollPost(ctx, pollID, channel) {
+		return reply(text)
+	}
+
+	return announce(text)
+}
+
+// notifyRemoval sends direct messages to users whose votes lost the removed option.
+func (c *Client) notifyRemoval(ctx context.Context, log *slog.Logger, pollID uint64, channel string, removal *entity.OptionRemoval) {
+	if len(removal.Votes) == 0 {
+		return
+	}
+
+	info, err := c.service.VoteService.GetPollInfo(ctx, pollID, channel)
+	if err != nil {
+		log.Error("failed to get poll to notify voters", slog.Uint64("pollID", pollID), sl.Error(err))
+		return
+	}
+
+	for _, vote := range removal.Votes {
+		text := formatRemovalNotice(info, removal.Option, vote)
+		if err := c.sendDirect(vote.User, text); err != nil {
+			log.Error("failed to notify voter about removed option", slog.Uint64("pollID", pollID), slog.String("user_id", vote.User), sl.Error(err))
+		}
+	}
+}
+
+// help responds with list of commands or with help of the command given in argument.
+func help(req commandRequest) commandResponse {
+	name := req.args.String(argCommand.Name)
+	if name == "" {
+		return reply(formatHelp())
+	}
+
+	cmd, ok := commands.Lookup(name)
+	if !ok {
+		return reply(fmt.Sprintf(
+			"unknown command %s%s, see `%s%s` for the list of commands",
+			commandPrefix, strings.TrimPrefix(name, commandPrefix), commandPrefix, cmdHelp,
+		))
+	}
+
+	return reply(commands.Usage(cmd))
+}
+
+// channelName returns name of the channel shown in Mattermost, or channel ID in case channel isn't found.
+func (c *Client) channelName(channelID string) string {
+	const op = "bot.client.channelName"
+
+	channel, _, err := c.mattermostClient.GetChannel(channelID, "")
+	if err != nil {
+		c.l.Error("failed to get channel", slog.String("op", op), slog.String("channel_id", channelID), sl.Error(err))
+		return channelID
+	}
+
+	// Direct and group messages don't have display names.
+	if channel.DisplayName == "" {
+		return channel.Name
+	}
+
+	return channel.DisplayName
+}
+
+// deletePost removes post from the channel.
+// Bot account needs permission to delete others' posts.
+func (c *Client) deletePost(postID string) {
+	const op = "bot.client.deletePost"
 
 	log := c.l.With(slog.String("op", op))
 
+	if _, err := c.mattermostClient.DeletePost(postID); err != nil {
+		log.Error("failed to delete post", slog.String("post_id", postID), sl.Error(err))
+	}
+}
+
+// deleteAnonymousCommand deletes post with the command in anonymous poll,
+// since it's visible to everyone in the channel and may contain choice or receipt.
+func (c *Client) deleteAnonymousCommand(ctx context.Context, req commandRequest, pollID uint64) {
+	if req.postID == "" {
+		return
+	}
+
+	poll, err := c.service.PollService.GetPoll(ctx, pollID, req.channelID)
+	if err == nil && poll.IsAnonymous {
+		c.deletePost(req.postID)
+	}
+}
+
+// sendWhisper shows ephemeral response only to the user in the channel.
+// Secret responses are lost in case ephemeral messages are denied.
+func (c *Client) sendWhisper(channel, userID string, resp commandResponse, replyToID string) error {
+	if resp.secret {
+		err := c.createEphemeral(channel, userID, resp.text, replyToID)
+		if err != nil {
+			c.l.Error("failed to send secret ephemeral message", slog.String("user_id", userID), sl.Error(err))
+		}
+		return err
+	}
+
+	return c.sendEphemeral(channel, userID, resp.text, replyToID)
+}
+
+// sendEphemeral shows message only to the user in the channel.
+// Creating ephemeral posts requires permission which bot accounts don't have by default,
+// so the message is sent to direct channel with the user in case it's denied.
+func (c *Client) sendEphemeral(channel, userID, message, replyToID string) error {
+	const op = "bot.client.sendEphemeral"
+
+	log := c.l.With(slog.String("op", op))
+
+	err := c.createEphemeral(channel, userID, message, replyToID)
+	if err == nil {
+		return nil
+	}
+	log.Warn("failed to send ephemeral message, sending direct message", slog.String("user_id", userID), sl.Error(err))
+
+	return c.sendDirect(userID, message)
+}
+
+// createEphemeral creates ephemeral post, it isn't stored by Mattermost.
+func (c *Client) createEphemeral(channel, userID, message, replyToID string) error {
+	const op = "bot.client.createEphemeral"
+
+	_, _, err := c.mattermostClient.CreatePostEphemeral(&model.PostEphemeral{
+		UserID: userID,
+		Post:   &model.Post{ChannelId: channel, Message: message, RootId: replyToID},
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// sendDirect sends message to direct channel with the user.
+func (c *Client) sendDirect(userID, message string) error {
+	const op = "bot.client.sendDirect"
+
+	direct, _, err := c.mattermostClient.CreateDirectChannel(c.mattermostUser.Id, userID)
+	if err != nil {
+		c.l.Error("failed to create direct channel", slog.String("op", op), slog.String("user_id", userID), sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c.sendMessage(direct.Id, message, "")
+}
+
+func (c *Client) sendMessage(channel, message, replyToID string) error {
 	post := &model.Post{}
 
 	post.ChannelId = channel
 	post.Message = message
 	post.RootId = replyToID
 
-	if post, resp, err := c.mattermostClient.CreatePost(post); err != nil {
-		log.Error("failed to send message", sl.Error(err))
-	} else {
-		log.Debug(
-			"sended message",
-			slog.String("text", message), slog.String("channel", post.ChannelId),
-			slog.Int("resp", resp.StatusCode),
-		)
-	}
+	return c.createPost(post)
 }
 
-func pollIDFromString(pollIDStr string) (uint64, error) {
-	const op = "bot.client.pollIDFromString"
+// createPost sends the post, e.g. with attachments or files.
+func (c *Client) createPost(post *model.Post) error {
+	const op = "bot.client.createPost"
 
-	pollID, err := strconv.Atoi(pollIDStr)
+	log := c.l.With(slog.String("op", op))
+
+	post, resp, err := c.mattermostClient.CreatePost(post)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		log.Error("failed to send message", sl.Error(err))
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return uint64(pollID), nil
+	log.Debug(
+		"sended message",
+		slog.String("text", post.Message), slog.String("channel", post.ChannelId),
+		slog.Int("resp", resp.StatusCode),
+	)
+
+	return nil
 }
 
-func optsFromString(optionsStr string) ([]uint64, error) {
-	const op = "bot.client.optsFromString"
+// choiceFromString parses options chosen in vote, e.g. "1 3".
+// Options of score polls are given with scores, e.g. "1:5 3:0".
+// scores[i] is a score of opts[i], scores is empty if no scores were given.
+func choiceFromString(choiceStr string) (opts []uint64, scores []uint64, err error) {
+	const op = "bot.client.choiceFromString"
 
-	optsStr := strings.Split(optionsStr, " ")
-	opts := make([]uint64, 0, len(optsStr))
-	for _, optStr := range optsStr {
-		opt, err := strconv.Atoi(optStr)
+	fields := strings.Fields(choiceStr)
+	isScored := len(fields) != 0 && strings.Contains(fields[0], ":")
+
+	opts = make([]uint64, 0, len(fields))
+	for _, f := range fields {
+		optStr, scoreStr, hasScore := strings.Cut(f, ":")
+		if hasScore != isScored {
+			return nil, nil, fmt.Errorf("%s: either all options or none of them must have scores", op)
+		}
+
+		opt, err := strconv.ParseUint(optStr, 10, 64)
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		opts = append(opts, opt)
+
+		if hasScore {
+			score, err := strconv.ParseUint(scoreStr, 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", op, err)
+			}
+			scores = append(scores, score)
 		}
-		opts = append(opts, uint64(opt))
 	}
 
-	return opts, nil
+	return opts, scores, nil
 }
@@ -0,0 +1,555 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/service"
+	"vote-bot/pkg/sl"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+const deadlineLayout = "2006-01-02 15:04 MST"
+
+// emptyResults forms results of the poll nobody voted in yet.
+func emptyResults(poll *entity.Poll, options []entity.Option) *entity.Results {
+	results := &entity.Results{
+		Poll:    *poll,
+		Options: make([]entity.OptionResult, 0, len(options)),
+	}
+	for _, option := range options {
+		results.Options = append(results.Options, entity.OptionResult{Option: option})
+	}
+
+	return results
+}
+
+// formatPoll forms text of the poll post with the current results.
+func formatPoll(results *entity.Results) string {
+	poll := &results.Poll
+	prefix := pollPrefix(poll.Type)
+
+	var b strings.Builder
+	if poll.IsFinished {
+		b.WriteString(fmt.Sprintf("**%spoll is finished**\n", prefix))
+	}
+	b.WriteString(fmt.Sprintf("New %spoll created: %s\nID: %d\n", prefix, poll.Name, poll.ID))
+	if poll.IsAnonymous {
+		b.WriteString("Votes are anonymous\n")
+	}
+	if poll.ShowVoters {
+		b.WriteString("Voters are listed in results\n")
+	}
+	if !poll.ClosesAt.IsZero() && !poll.IsFinished {
+		b.WriteString(fmt.Sprintf("Closes at: %s\n", poll.ClosesAt.UTC().Format(deadlineLayout)))
+	}
+	switch {
+	case poll.Type == entity.PollTypeScore:
+		b.WriteString(fmt.Sprintf("Rate options from 0 to %d\n", entity.MaxScore))
+	case poll.Type == entity.PollTypeApproval && poll.MaxChoices != 0:
+		b.WriteString(fmt.Sprintf("Choose up to %d options\n", poll.MaxChoices))
+	}
+	for _, opt := range results.Options {
+		b.WriteString(fmt.Sprintf("%d) %s: %s\n", opt.Option.Num, opt.Option.Name, formatVotes(poll.Type, opt)))
+	}
+
+	return b.String()
+}
+
+// formatVotes forms number of votes for the option,
+// for score polls it's average score and number of ratings.
+func formatVotes(pollType entity.PollType, opt entity.OptionResult) string {
+	if pollType == entity.PollTypeScore {
+		return fmt.Sprintf("%.2f avg (%d ratings)", opt.Average, opt.Votes)
+	}
+
+	return fmt.Sprintf("%d", opt.Votes)
+}
+
+// pollPrefix returns word which is written before "poll" in messages, e.g. "multipoll".
+func pollPrefix(pollType entity.PollType) string {
+	switch pollType {
+	case entity.PollTypeMulti:
+		return "multi"
+	case entity.PollTypeRanked:
+		return "ranked "
+	case entity.PollTypeScore:
+		return "score "
+	case entity.PollTypeApproval:
+		return "approval "
+	}
+
+	return ""
+}
+
+// pollTypeName returns human readable type of the poll.
+func pollTypeName(pollType entity.PollType) string {
+	switch pollType {
+	case entity.PollTypeMulti:
+		return "multiple choice"
+	case entity.PollTypeRanked:
+		return "ranked choice"
+	case entity.PollTypeScore:
+		return fmt.Sprintf("score from 0 to %d", entity.MaxScore)
+	case entity.PollTypeApproval:
+		return "approval"
+	}
+
+	return "single choice"
+}
+
+// formatPollInfo forms markdown text with definition of the poll.
+// reopener is a name of the user who reopened the poll, it's empty if poll wasn't reopened.
+func formatPollInfo(info *entity.PollInfo, creator string, reopener string) string {
+	poll := &info.Poll
+
+	status := "open"
+	if poll.IsFinished {
+		status = "finished"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("#### Poll %d: %s\n", poll.ID, poll.Name))
+	b.WriteString(fmt.Sprintf("Created by: %s\n", creator))
+	if reopener != "" {
+		b.WriteString(fmt.Sprintf("Reopened by: %s at %s\n", reopener, poll.ReopenedAt.UTC().Format(deadlineLayout)))
+	}
+	b.WriteString(fmt.Sprintf("Type: %s | Status: %s | Voters: %d", pollTypeName(poll.Type), status, info.Voters))
+	if poll.IsAnonymous {
+		b.WriteString(" | Anonymous")
+	}
+	if poll.ShowVoters {
+		b.WriteString(" | Voters shown")
+	}
+	b.WriteString("\n")
+	if !poll.ClosesAt.IsZero() {
+		b.WriteString(fmt.Sprintf("Deadline: %s\n", poll.ClosesAt.UTC().Format(deadlineLayout)))
+	}
+	if poll.Type == entity.PollTypeApproval && poll.MaxChoices != 0 {
+		b.WriteString(fmt.Sprintf("Up to %d options in a vote\n", poll.MaxChoices))
+	}
+	b.WriteString("\n")
+	for _, opt := range info.Options {
+		b.WriteString(fmt.Sprintf("%d) %s\n", opt.Num, opt.Name))
+	}
+
+	return b.String()
+}
+
+// formatUserVote forms text with options chosen by the user in the poll.
+func formatUserVote(vote *entity.UserVote) string {
+	poll := &vote.Poll
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("#### Your vote in poll %d: %s\n", poll.ID, poll.Name))
+	if poll.Type == entity.PollTypeRanked {
+		b.WriteString("In order of preference:\n")
+	}
+	for i, opt := range vote.Options {
+		if poll.Type == entity.PollTypeScore && i < len(vote.Scores) {
+			b.WriteString(fmt.Sprintf("%d) %s: %d\n", opt.Num, opt.Name, vote.Scores[i]))
+			continue
+		}
+		b.WriteString(fmt.Sprintf("%d) %s\n", opt.Num, opt.Name))
+	}
+	if poll.IsFinished {
+		b.WriteString("\nThe poll is finished, the vote can't be changed.\n")
+	}
+
+	return b.String()
+}
+
+// formatRemovalNotice forms message to the voter whose vote lost the removed option.
+func formatRemovalNotice(info *entity.PollInfo, removed entity.Option, vote entity.Vote) string {
+	poll := &info.Poll
+
+	text := fmt.Sprintf("Option \"%s\" was removed from poll %d: %s by its creator.\n", removed.Name, poll.ID, poll.Name)
+	if len(vote.OptionIDs) == 0 {
+		return text + fmt.Sprintf("Nothing else was chosen in your vote, so it was removed. You can vote again with `%s%s %d`.\n", commandPrefix, cmdVote, poll.ID)
+	}
+
+	byNum := make(map[uint64]entity.Option, len(info.Options))
+	for _, opt := range info.Options {
+		byNum[opt.Num] = opt
+	}
+	userVote := &entity.UserVote{Poll: *poll, Scores: vote.Scores}
+	for _, num := range vote.OptionIDs {
+		userVote.Options = append(userVote.Options, byNum[num])
+	}
+
+	return text + formatUserVote(userVote)
+}
+
+// formatPollEdits forms list of edits of the poll.
+// names maps IDs of editors to their usernames, editors without names are shown by IDs.
+func formatPollEdits(pollID uint64, edits []entity.PollEdit, names map[string]string) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("#### History of poll %d\n", pollID))
+	for _, edit := range edits {
+		var change string
+		switch edit.Action {
+		case entity.EditRenamePoll:
+			change = fmt.Sprintf("renamed the poll from \"%s\" to \"%s\"", edit.OldValue, edit.NewValue)
+		case entity.EditAddOption:
+			change = fmt.Sprintf("added option %d) %s", edit.OptionNum, edit.NewValue)
+		case entity.EditRenameOption:
+			change = fmt.Sprintf("renamed option %d from \"%s\" to \"%s\"", edit.OptionNum, edit.OldValue, edit.NewValue)
+		case entity.EditRemoveOption:
+			change = fmt.Sprintf("removed option %d) %s", edit.OptionNum, edit.OldValue)
+		case entity.EditMoveOption:
+			change = fmt.Sprintf("moved option %s to position %s", edit.OldValue, edit.NewValue)
+		case entity.EditReopenPoll:
+			change = "reopened the poll"
+			if closesAt, err := time.Parse(time.RFC3339, edit.NewValue); err == nil {
+				change += fmt.Sprintf(" until %s", closesAt.UTC().Format(deadlineLayout))
+			}
+		default:
+			change = string(edit.Action)
+		}
+		editor := edit.Editor
+		if name, ok := names[editor]; ok {
+			editor = "@" + name
+		}
+		b.WriteString(fmt.Sprintf("- %s %s %s\n", edit.EditedAt.UTC().Format(deadlineLayout), editor, change))
+	}
+
+	return b.String()
+}
+
+// formatPollPage forms markdown table of polls with link to the next page.
+// Column with channel is added in case channels are given by their IDs.
+// next is a command to get the next page with.
+func formatPollPage(title string, page *entity.PollPage, channels map[string]string, next string) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("#### %s\n", title))
+	if channels != nil {
+		b.WriteString("| ID | Name | Channel | Status | Voters |\n")
+		b.WriteString("|--:|:--|:--|:--|--:|\n")
+	} else {
+		b.WriteString("| ID | Name | Status | Voters |\n")
+		b.WriteString("|--:|:--|:--|--:|\n")
+	}
+
+	for _, summary := range page.Polls {
+		poll := &summary.Poll
+
+		status := "open"
+		if poll.IsFinished {
+			status = "finished"
+		}
+
+		name := escapeCell(poll.Name)
+		if channels != nil {
+			b.WriteString(fmt.Sprintf(
+				"| %d | %s | %s | %s | %d |\n",
+				poll.ID, name, escapeCell(channels[poll.Channel]), status, summary.Voters,
+			))
+			continue
+		}
+		b.WriteString(fmt.Sprintf("| %d | %s | %s | %d |\n", poll.ID, name, status, summary.Voters))
+	}
+
+	if page.NextBefore != 0 {
+		b.WriteString(fmt.Sprintf("\nMore: `%s`\n", next))
+	}
+
+	return b.String()
+}
+
+// escapeCell escapes text put in cell of markdown table.
+func escapeCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+// barWidth is a number of cells in bar charts of results.
+const barWidth = 10
+
+// formatResults forms markdown text of poll results: poll info and table
+// of options sorted by number of votes (by average score in score polls).
+// Ranked polls also get rounds of instant-runoff counting.
+func formatResults(results *entity.Results) string {
+	poll := &results.Poll
+
+	status := "open"
+	if poll.IsFinished {
+		status = "finished"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("#### Results of %spoll %d: %s\n", pollPrefix(poll.Type), poll.ID, poll.Name))
+	b.WriteString(fmt.Sprintf("Status: %s | Voters: %d | Selections: %d", status, results.TotalVoters, results.TotalSelections))
+	if poll.IsAnonymous {
+		b.WriteString(" | Anonymous")
+	}
+	b.WriteString("\n\n")
+
+	// Options are sorted in a copy, since their order is used to find options by number.
+	options := slices.Clone(results.Options)
+	slices.SortStableFunc(options, func(a, b entity.OptionResult) int {
+		if poll.Type == entity.PollTypeScore {
+			return cmp.Compare(b.Average, a.Average)
+		}
+		return cmp.Compare(b.Votes, a.Votes)
+	})
+
+	if poll.Type == entity.PollTypeScore {
+		b.WriteString(fmt.Sprintf("| # | Option | Average | Ratings | Scores 0-%d | |\n", entity.MaxScore))
+		b.WriteString("|--:|:--|--:|--:|:--|:--|\n")
+	} else {
+		b.WriteString("| # | Option | Votes | % | |\n")
+		b.WriteString("|--:|:--|--:|--:|:--|\n")
+	}
+	for _, opt := range options {
+		name := opt.Option.Name
+		if results.IsWinner(opt.Option.Num) {
+			name = "**" + name + "**"
+		}
+
+		if poll.Type == entity.PollTypeScore {
+			scores := make([]string, 0, len(opt.Distribution))
+			for _, count := range opt.Distribution {
+				scores = append(scores, fmt.Sprintf("%d", count))
+			}
+			b.WriteString(fmt.Sprintf(
+				"| %d | %s | %.2f | %d | %s | `%s` |\n",
+				opt.Option.Num, name, opt.Average, opt.Votes,
+				strings.Join(scores, " / "), bar(opt.Average/entity.MaxScore),
+			))
+			continue
+		}
+
+		b.WriteString(fmt.Sprintf(
+			"| %d | %s | %d | %.1f%% | `%s` |\n",
+			opt.Option.Num, name, opt.Votes, opt.Percent, bar(opt.Percent/100),
+		))
+	}
+	b.WriteString("\n")
+
+	for i, round := range results.Rounds {
+		b.WriteString(fmt.Sprintf("Round %d:", i+1))
+		for j, votes := range round.Votes {
+			if votes == 0 && !slices.Contains(round.Eliminated, uint64(j+1)) {
+				continue
+			}
+			b.WriteString(fmt.Sprintf(" %s: %d;", optionName(results, uint64(j+1)), votes))
+		}
+		if round.Exhausted != 0 {
+			b.WriteString(fmt.Sprintf(" exhausted: %d;", round.Exhausted))
+		}
+		if len(round.Eliminated) != 0 {
+			b.WriteString(" eliminated: " + optionNames(results, round.Eliminated))
+		}
+		b.WriteString("\n")
+	}
+
+	switch {
+	case len(results.Winners) == 0:
+		b.WriteString("No winner\n")
+	case results.IsTie():
+		b.WriteString(fmt.Sprintf("Tie: %s\n", optionNames(results, results.Winners)))
+	default:
+		b.WriteString(fmt.Sprintf("Winner: %s\n", optionName(results, results.Winners[0])))
+	}
+
+	return b.String()
+}
+
+// formatVoters forms list of voters of each option in order of option numbers.
+// Voters are mentioned by usernames from names, the ones not found there are given by IDs.
+func formatVoters(results *entity.Results, names map[string]string) string {
+	var b strings.Builder
+
+	b.WriteString("Voters:\n")
+	for _, opt := range results.Options {
+		voters := make([]string, 0, len(opt.Voters))
+		for _, id := range opt.Voters {
+			if name, ok := names[id]; ok {
+				voters = append(voters, "@"+name)
+				continue
+			}
+			voters = append(voters, id)
+		}
+		if len(voters) == 0 {
+			voters = append(voters, "nobody")
+		}
+		b.WriteString(fmt.Sprintf("- %d) %s: %s\n", opt.Option.Num, opt.Option.Name, strings.Join(voters, ", ")))
+	}
+
+	return b.String()
+}
+
+// voterNames returns usernames of voters of the results by their IDs.
+func (c *Client) voterNames(results *entity.Results) map[string]string {
+	var ids []string
+	for _, opt := range results.Options {
+		ids = append(ids, opt.Voters...)
+	}
+
+	return c.usernames(ids)
+}
+
+// resultsText forms text of the results, with voters of each option in case they are given.
+func (c *Client) resultsText(results *entity.Results) string {
+	text := formatResults(results)
+	if results.HasVoters() {
+		text += "\n" + formatVoters(results, c.voterNames(results))
+	}
+
+	return text
+}
+
+// bar draws bar chart cell filled by share from 0 to 1.
+func bar(share float64) string {
+	filled := int(math.Round(share * barWidth))
+	filled = max(0, min(filled, barWidth))
+
+	return strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+}
+
+// optionName returns name of the option by its number.
+func optionName(results *entity.Results, num uint64) string {
+	if num == 0 || num > uint64(len(results.Options)) {
+		return fmt.Sprintf("%d)", num)
+	}
+
+	return fmt.Sprintf("%d) %s", num, results.Options[num-1].Option.Name)
+}
+
+func optionNames(results *entity.Results, nums []uint64) string {
+	names := make([]string, 0, len(nums))
+	for _, num := range nums {
+		names = append(names, optionName(results, num))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// AnnouncePoll posts a new poll to its channel and saves ID of the post,
+// so that it could be updated later.
+func (c *Client) AnnouncePoll(ctx context.Context, poll *entity.Poll, options []entity.Option) error {
+	const op = "bot.client.AnnouncePoll"
+
+	results := emptyResults(poll, options)
+
+	post := &model.Post{
+		ChannelId: poll.Channel,
+		Message:   formatPoll(results),
+	}
+	model.ParseSlackAttachment(post, c.pollAttachments(results))
+
+	post, _, err := c.mattermostClient.CreatePost(post)
+	if err != nil {
+		return fmt.Errorf("%s: failed to create post: %w", op, err)
+	}
+
+	if err := c.service.PollService.SetPollPost(ctx, poll.ID, post.Id); err != nil {
+		return fmt.Errorf("%s: failed to save poll post: %w", op, err)
+	}
+
+	return nil
+}
+
+// RefreshPollPost updates the post which announces the poll
+// with the current number of votes and poll status.
+//
+// It returns false in case poll post wasn't updated, e.g. poll doesn't have one.
+func (c *Client) RefreshPollPost(ctx context.Context, pollID uint64, channel string) bool {
+	const op = "bot.client.RefreshPollPost"
+
+	log := c.l.With(slog.String("op", op), slog.Uint64("poll_id", pollID))
+
+	results, err := c.service.VoteService.GetTally(ctx, pollID, channel)
+	if err != nil {
+		log.Error("failed to get poll tally", sl.Error(err))
+		return false
+	}
+	poll := &results.Poll
+
+	if poll.PostID == "" {
+		return false
+	}
+
+	message := formatPoll(results)
+	props := model.StringInterface{}
+	if attachments := c.pollAttachments(results); len(attachments) != 0 {
+		props["attachments"] = attachments
+	}
+
+	_, _, err = c.mattermostClient.PatchPost(poll.PostID, &model.PostPatch{
+		Message: &message,
+		Props:   &props,
+	})
+	if err != nil {
+		log.Error("failed to update poll post", slog.String("post_id", poll.PostID), sl.Error(err))
+		return false
+	}
+
+	return true
+}
+
+// NotifyPollFinished updates post of the poll finished by deadline
+// and posts its final results to the channel.
+func (c *Client) NotifyPollFinished(ctx context.Context, poll entity.Poll) {
+	const op = "bot.client.NotifyPollFinished"
+
+	log := c.l.With(slog.String("op", op), slog.Uint64("poll_id", poll.ID))
+
+	c.RefreshPollPost(ctx, poll.ID, poll.Channel)
+
+	text := fmt.Sprintf("Poll %d \"%s\" was finished by deadline.\n", poll.ID, poll.Name)
+
+	results, err := c.service.VoteService.GetResults(ctx, poll.ID, poll.Channel)
+	switch {
+	case errors.Is(err, service.ErrNoVotesInPoll):
+		text += "There were no votes."
+	case err != nil:
+		log.Error("failed to get poll results", sl.Error(err))
+	default:
+		text += c.resultsText(results)
+	}
+
+	// Post is remembered to mark results as superseded in case poll is reopened.
+	post, _, err := c.mattermostClient.CreatePost(&model.Post{ChannelId: poll.Channel, Message: text})
+	if err != nil {
+		log.Error("failed to post poll results", sl.Error(err))
+		return
+	}
+
+	if err := c.service.PollService.SetResultsPost(ctx, poll.ID, post.Id); err != nil {
+		log.Error("failed to save results post", slog.String("post_id", post.Id), sl.Error(err))
+	}
+}
+
+// supersedeResults marks results posted when the poll was finished as not final,
+// since the poll was reopened.
+func (c *Client) supersedeResults(log *slog.Logger, reopening *entity.Reopening) {
+	postID := reopening.SupersededPostID
+	if postID == "" {
+		return
+	}
+
+	post, _, err := c.mattermostClient.GetPost(postID, "")
+	if err != nil {
+		log.Error("failed to get results post", slog.String("post_id", postID), sl.Error(err))
+		return
+	}
+
+	poll := &reopening.Poll
+	message := fmt.Sprintf(
+		"**Superseded:** poll was reopened by @%s at %s, these results aren't final.\n\n%s",
+		c.username(poll.ReopenedBy), poll.ReopenedAt.UTC().Format(deadlineLayout), post.Message,
+	)
+	if _, _, err := c.mattermostClient.PatchPost(postID, &model.PostPatch{Message: &message}); err != nil {
+		log.Error("failed to mark results as superseded", slog.String("post_id", postID), sl.Error(err))
+	}
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/service"
+	"vote-bot/pkg/sl"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// recentPostsLimit is a number of submitted post IDs remembered to skip duplicates.
+const recentPostsLimit = 1000
+
+// recentPosts is a fixed-size set of post IDs, the oldest ones are evicted first.
+type recentPosts struct {
+	ids   map[string]struct{}
+	order []string
+	next  int
+}
+
+func newRecentPosts(size int) *recentPosts {
+	return &recentPosts{
+		ids:   make(map[string]struct{}, size),
+		order: make([]string, size),
+	}
+}
+
+// add remembers post ID. It reports false in case it's already there.
+func (r *recentPosts) add(id string) bool {
+	if _, ok := r.ids[id]; ok {
+		return false
+	}
+
+	delete(r.ids, r.order[r.next])
+	r.order[r.next] = id
+	r.next = (r.next + 1) % len(r.order)
+	r.ids[id] = struct{}{}
+
+	return true
+}
+
+// replayMissed submits posts created in the bot's channels while websocket was disconnected.
+// Posts are fetched since the cursor of each channel, so posts missed before restart are replayed too.
+func (c *Client) replayMissed(log *slog.Logger) {
+	channels, _, err := c.mattermostClient.GetChannelsForTeamForUser(c.mattermostTeam.Id, c.mattermostUser.Id, false, "")
+	if err != nil {
+		log.Error("failed to get channels to replay missed posts", sl.Error(err))
+		return
+	}
+
+	replayed := 0
+	for _, channel := range channels {
+		if c.stopped() {
+			return
+		}
+
+		n, err := c.replayChannel(log, channel)
+		if err != nil {
+			log.Error("failed to replay missed posts", slog.String("channel_id", channel.Id), sl.Error(err))
+			continue
+		}
+		replayed += n
+	}
+
+	if replayed != 0 {
+		log.Info("replayed missed posts", slog.Int("count", replayed))
+	}
+}
+
+// replayChannel submits posts of the channel after its cursor and returns their number.
+func (c *Client) replayChannel(log *slog.Logger, channel *model.Channel) (int, error) {
+	const op = "bot.client.replayChannel"
+
+	cursor, err := c.loadCursor(channel.Id)
+	if errors.Is(err, service.ErrCursorNotFound) {
+		// Bot hasn't listened to the channel before, so it has nothing to catch up with.
+		// Posts are tracked from the last one in the channel. Cursor is created by the worker
+		// of the channel, so that it doesn't overwrite cursor of the post handled there.
+		cursor := entity.Cursor{Channel: channel.Id, LastPostAt: channel.LastPostAt}
+		c.events.Submit(channel.Id, func() { c.initCursor(cursor) })
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if channel.LastPostAt < cursor.LastPostAt ||
+		channel.LastPostAt == cursor.LastPostAt && len(cursor.PostIDs) == 0 {
+		return 0, nil
+	}
+
+	// Posts updated since the cursor are returned, hence the old ones are filtered out below.
+	list, _, err := c.mattermostClient.GetPostsSince(channel.Id, cursor.LastPostAt, false)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to get posts: %w", op, err)
+	}
+
+	posts := list.ToSlice()
+	slices.SortFunc(posts, func(a, b *model.Post) int {
+		return cmp.Or(cmp.Compare(a.CreateAt, b.CreateAt), cmp.Compare(a.Id, b.Id))
+	})
+
+	replayed := 0
+	for _, post := range posts {
+		// System messages and deleted posts aren't commands.
+		if post.DeleteAt != 0 || post.Type != "" || cursor.IsHandled(post.Id, post.CreateAt) {
+			continue
+		}
+		if c.submitPost(log, post) {
+			replayed++
+		}
+	}
+
+	return replayed, nil
+}
+
+// loadCursor returns copy of the channel cursor from the cache or from the repo.
+func (c *Client) loadCursor(channel string) (*entity.Cursor, error) {
+	if cursor, ok := c.cachedCursor(channel); ok {
+		return cursor, nil
+	}
+
+	ctx, cancel := c.requestContext(c.ctx)
+	defer cancel()
+
+	cursor, err := c.service.CursorService.GetCursor(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cursorsMu.Lock()
+	defer c.cursorsMu.Unlock()
+
+	// Cursor could be advanced by handled post while it was loaded.
+	if cached, ok := c.cursors[channel]; ok {
+		return copyCursor(cached), nil
+	}
+	c.cursors[channel] = copyCursor(cursor)
+
+	return cursor, nil
+}
+
+// cachedCursor returns copy of the channel cursor from the cache.
+func (c *Client) cachedCursor(channel string) (*entity.Cursor, bool) {
+	c.cursorsMu.Lock()
+	defer c.cursorsMu.Unlock()
+
+	cursor, ok := c.cursors[channel]
+	if !ok {
+		return nil, false
+	}
+
+	return copyCursor(cursor), true
+}
+
+// advanceCursor moves cursor of the channel to the handled post and persists it.
+// It's called by the worker of the channel, so cursors of one channel are saved in order.
+func (c *Client) advanceCursor(post *model.Post) {
+	const op = "bot.client.advanceCursor"
+
+	c.cursorsMu.Lock()
+	cursor, ok := c.cursors[post.ChannelId]
+	if !ok {
+		cursor = &entity.Cursor{Channel: post.ChannelId}
+		c.cursors[post.ChannelId] = cursor
+	}
+	if !cursor.Advance(post.Id, post.CreateAt) {
+		c.cursorsMu.Unlock()
+		return
+	}
+	advanced := copyCursor(cursor)
+	c.cursorsMu.Unlock()
+
+	if err := c.saveCursor(*advanced); err != nil {
+		c.l.Error(
+			"failed to save cursor, post may be replayed after restart",
+			slog.String("op", op),
+			slog.String("channel_id", post.ChannelId),
+			sl.Error(err),
+		)
+	}
+}
+
+// initCursor creates cursor of the channel in case there is none yet.
+func (c *Client) initCursor(cursor entity.Cursor) {
+	const op = "bot.client.initCursor"
+
+	c.cursorsMu.Lock()
+	if _, ok := c.cursors[cursor.Channel]; ok {
+		c.cursorsMu.Unlock()
+		return
+	}
+	c.cursors[cursor.Channel] = copyCursor(&cursor)
+	c.cursorsMu.Unlock()
+
+	if err := c.saveCursor(cursor); err != nil {
+		c.l.Error("failed to save cursor", slog.String("op", op), slog.String("channel_id", cursor.Channel), sl.Error(err))
+	}
+}
+
+// saveCursor persists the cursor.
+func (c *Client) saveCursor(cursor entity.Cursor) error {
+	const op = "bot.client.saveCursor"
+
+	ctx, cancel := c.requestContext(c.ctx)
+	defer cancel()
+
+	if err := c.service.CursorService.SaveCursor(ctx, cursor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func copyCursor(cursor *entity.Cursor) *entity.Cursor {
+	cp := *cursor
+	cp.PostIDs = slices.Clone(cursor.PostIDs)
+
+	return &cp
+}
@@ -0,0 +1,296 @@
+package client
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"vote-bot/internal/entity"
+	"vote-bot/pkg/sl"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// CommandPath is a path of the endpoint for slash commands and outgoing webhooks.
+const CommandPath = "/commands"
+
+// RegisterHandlers registers HTTP handlers of Mattermost integrations
+// which are configured.
+func (c *Client) RegisterHandlers(mux *http.ServeMux) {
+	if len(c.config.CommandTokens) != 0 {
+		mux.HandleFunc("POST "+CommandPath, c.HandleCommand)
+	} else {
+		c.l.Warn("no command tokens configured, slash commands and outgoing webhooks are disabled")
+	}
+
+	if c.ActionsEnabled() {
+		mux.HandleFunc("POST "+VoteActionPath, c.HandleVoteAction)
+	} else {
+		c.l.Warn("actions url or token is not configured, polls are created without vote buttons")
+	}
+}
+
+// slashSubcommands maps subcommands of "/poll" slash command
+// to the bot commands they are executed as.
+var slashSubcommands = map[string]string{
+	"create":          cmdCreatePoll,
+	"create_multi":    cmdCreateMultiPoll,
+	"create_ranked":   cmdCreateRankedPoll,
+	"create_score":    cmdCreateScorePoll,
+	"create_approval": cmdCreateApprovalPoll,
+	"finish":          cmdFinishPoll,
+	"reopen":          cmdReopenPoll,
+	"delete":          cmdDeletePoll,
+	"vote":            cmdVote,
+	"retract":         cmdRetractVote,
+	"results":         cmdGetResults,
+	"info":            cmdPollInfo,
+	"list":            cmdListPolls,
+	"mine":            cmdMyPolls,
+	"myvote":          cmdMyVote,
+	"export":          cmdExport,
+	"voters":          cmdVoters,
+	"rename":          cmdRenamePoll,
+	"add_option":      cmdAddOption,
+	"rename_option":   cmdRenameOption,
+	"remove_option":   cmdRemoveOption,
+	"move_option":     cmdMoveOption,
+	"history":         cmdPollHistory,
+	"help":            cmdHelp,
+}
+
+const slashUsage = "Usage: /poll <subcommand> [args]\n" +
+	"Subcommands: create, create_multi, create_ranked, create_score, create_approval, vote, retract, results, info, list, mine, myvote, voters, export, rename, add_option, rename_option, remove_option, move_option, history, finish, reopen, delete, help"
+
+// commandPayload contains fields of slash command and outgoing webhook
+// requests that are used by the bot.
+type commandPayload struct {
+	Token       string `json:"token"`
+	ChannelID   string `json:"channel_id"`
+	UserID      string `json:"user_id"`
+	PostID      string `json:"post_id"`
+	Text        string `json:"text"`
+	Command     string `json:"command"`
+	TriggerWord string `json:"trigger_word"`
+}
+
+// HandleCommand serves requests of Mattermost slash commands (e.g. "/poll vote 5")
+// and outgoing webhooks triggered by "!" commands.
+//
+// Commands are executed the same way as the ones received via websocket.
+// Posts of outgoing webhooks are claimed like posts from websocket,
+// so the command isn't executed twice in case both of them deliver it.
+func (c *Client) HandleCommand(w http.ResponseWriter, r *http.Request) {
+	const op = "bot.client.HandleCommand"
+
+	log := c.l.With(slog.String("op", op))
+
+	payload, err := decodeCommandPayload(r)
+	if err != nil {
+		log.Error("failed to decode command payload", sl.Error(err))
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if !c.validCommandToken(payload.Token) {
+		log.Warn("got command with invalid token", slog.String("channel_id", payload.ChannelID))
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	// Command text isn't logged, since it may contain choice in anonymous poll.
+	log.Info(
+		"got new command",
+		slog.String("command", payload.Command),
+		slog.String("channel_id", payload.ChannelID),
+		slog.String("user_id", payload.UserID),
+		slog.String("username", c.username(payload.UserID)),
+	)
+
+	// Outgoing webhooks send the whole message, slash commands send only text after the trigger.
+	msg := payload.Text
+	if payload.TriggerWord == "" {
+		text := strings.TrimLeft(payload.Text, " ")
+
+		// Subcommand may be followed either by arguments or by the next line.
+		subcommand, rest := text, ""
+		if i := strings.IndexAny(text, " \n"); i != -1 {
+			subcommand, rest = text[:i], text[i:]
+		}
+
+		cmd, ok := slashSubcommands[subcommand]
+		if !ok {
+			writeJSON(w, &model.CommandResponse{
+				ResponseType: model.CommandResponseTypeEphemeral,
+				Text:         slashUsage,
+			})
+			return
+		}
+		msg = commandPrefix + cmd + rest
+	}
+
+	req, ok := parseCommand(msg)
+	if !ok {
+		writeJSON(w, &model.CommandResponse{
+			ResponseType: model.CommandResponseTypeEphemeral,
+			Text:         slashUsage,
+		})
+		return
+	}
+	req.userID = payload.UserID
+	req.channelID = payload.ChannelID
+	req.postID = payload.PostID
+
+	ctx, cancel := c.requestContext(r.Context())
+	defer cancel()
+
+	webhook := payload.TriggerWord != "" && payload.PostID != ""
+	if webhook {
+		processed, claimed, err := c.service.CommandService.Claim(ctx, payload.PostID, payload.ChannelID)
+		if err != nil {
+			log.Error("failed to claim command", slog.String("message_id", payload.PostID), sl.Error(err))
+			text := failed("failed to handle command", err).text
+			writeJSON(w, &model.OutgoingWebhookResponse{Text: &text, ResponseType: model.OutgoingHookResponseTypeComment})
+			return
+		}
+		if !claimed {
+			// The post came via websocket as well, response is sent there.
+			log.Info(
+				"skipping command which was already handled",
+				slog.String("message_id", payload.PostID), slog.String("status", string(processed.Status)),
+			)
+			writeJSON(w, &model.OutgoingWebhookResponse{})
+			return
+		}
+	}
+
+	resp := c.executeCommand(ctx, req)
+
+	if webhook {
+		c.markSent(log, payload, resp)
+	}
+
+	// Files can't be attached to responses of slash commands and outgoing webhooks,
+	// so response with files is posted via API.
+	if len(resp.fileIDs) != 0 {
+		c.createPost(&model.Post{
+			ChannelId: payload.ChannelID,
+			Message:   resp.text,
+			RootId:    payload.PostID,
+			FileIds:   resp.fileIDs,
+		})
+		if payload.TriggerWord != "" {
+			writeJSON(w, &model.OutgoingWebhookResponse{})
+			return
+		}
+		writeJSON(w, &model.CommandResponse{ResponseType: model.CommandResponseTypeEphemeral})
+		return
+	}
+
+	if payload.TriggerWord != "" {
+		// Outgoing webhook responses are posted for everyone, so ephemeral ones are sent apart.
+		if resp.ephemeral {
+			c.sendWhisper(payload.ChannelID, payload.UserID, resp, payload.PostID)
+			writeJSON(w, &model.OutgoingWebhookResponse{})
+			return
+		}
+
+		text := resp.text
+		webhookResp := &model.OutgoingWebhookResponse{Text: &text, Attachments: resp.attachments}
+		if !resp.public {
+			webhookResp.ResponseType = model.OutgoingHookResponseTypeComment
+		}
+		writeJSON(w, webhookResp)
+		return
+	}
+
+	responseType := model.CommandResponseTypeEphemeral
+	if resp.public {
+		responseType = model.CommandResponseTypeInChannel
+	}
+	writeJSON(w, &model.CommandResponse{
+		ResponseType: responseType,
+		Text:         resp.text,
+		Attachments:  resp.attachments,
+	})
+}
+
+// markSent saves outcome of the command of outgoing webhook. Response is sent
+// by Mattermost as soon as the request is served, so it's saved as sent right away.
+func (c *Client) markSent(log *slog.Logger, payload *commandPayload, resp commandResponse) {
+	ctx, cancel := c.requestContext(c.ctx)
+	defer cancel()
+
+	cmd := entity.ProcessedCommand{
+		PostID:      payload.PostID,
+		Channel:     payload.ChannelID,
+		ProcessedAt: time.Now(),
+		Public:      resp.public,
+	}
+	if !resp.ephemeral {
+		cmd.Response = resp.text
+	}
+
+	if err := c.service.CommandService.MarkSent(ctx, cmd); err != nil {
+		log.Error("failed to save command outcome", slog.String("message_id", payload.PostID), sl.Error(err))
+	}
+}
+
+// validCommandToken checks token against the ones from config in constant time.
+func (c *Client) validCommandToken(token string) bool {
+	if token == "" {
+		return false
+	}
+
+	valid := false
+	for _, t := range c.config.CommandTokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// decodeCommandPayload reads payload either from form values (slash commands
+// and outgoing webhooks by default) or from JSON body (outgoing webhooks
+// with application/json content type).
+func decodeCommandPayload(r *http.Request) (*commandPayload, error) {
+	const op = "bot.client.decodeCommandPayload"
+
+	var payload commandPayload
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			return nil, fmt.Errorf("%s: failed to decode json: %w", op, err)
+		}
+
+		return &payload, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("%s: failed to parse form: %w", op, err)
+	}
+
+	payload = commandPayload{
+		Token:       r.Form.Get("token"),
+		ChannelID:   r.Form.Get("channel_id"),
+		UserID:      r.Form.Get("user_id"),
+		PostID:      r.Form.Get("post_id"),
+		Text:        r.Form.Get("text"),
+		Command:     r.Form.Get("command"),
+		TriggerWord: r.Form.Get("trigger_word"),
+	}
+
+	return &payload, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+	"vote-bot/pkg/sl"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// userDirectory resolves Mattermost user IDs to users.
+// Users are cached for ttl, cached user is dropped as soon as the user is updated in Mattermost.
+type userDirectory struct {
+	ttl time.Duration
+	// fetch gets users by IDs from Mattermost, users which aren't found are left out.
+	fetch func(ids []string) ([]*model.User, error)
+
+	mu    sync.Mutex
+	cache map[string]cachedUser
+}
+
+type cachedUser struct {
+	user      *model.User
+	expiresAt time.Time
+}
+
+func newUserDirectory(ttl time.Duration, fetch func(ids []string) ([]*model.User, error)) *userDirectory {
+	return &userDirectory{
+		ttl:   ttl,
+		fetch: fetch,
+		cache: make(map[string]cachedUser),
+	}
+}
+
+// users returns users by their IDs. Users which aren't cached are fetched in one request.
+// Users which failed to load are missing in the result, error is returned together with the cached ones.
+func (d *userDirectory) users(ids []string) (map[string]*model.User, error) {
+	users := make(map[string]*model.User, len(ids))
+	var missing []string
+
+	now := time.Now()
+	d.mu.Lock()
+	for _, id := range ids {
+		cached, ok := d.cache[id]
+		if !ok || now.After(cached.expiresAt) {
+			missing = append(missing, id)
+			continue
+		}
+		users[id] = cached.user
+	}
+	d.mu.Unlock()
+
+	slices.Sort(missing)
+	missing = slices.Compact(missing)
+
+	if len(missing) == 0 {
+		return users, nil
+	}
+
+	fetched, err := d.fetch(missing)
+	if err != nil {
+		return users, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Expired users are evicted here, so that users who left don't stay in cache forever.
+	for id, cached := range d.cache {
+		if now.After(cached.expiresAt) {
+			delete(d.cache, id)
+		}
+	}
+	expiresAt := time.Now().Add(d.ttl)
+	for _, user := range fetched {
+		d.cache[user.Id] = cachedUser{user: user, expiresAt: expiresAt}
+		users[user.Id] = user
+	}
+
+	return users, nil
+}
+
+// invalidate drops the user from cache, so that it's fetched again next time.
+func (d *userDirectory) invalidate(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.cache, id)
+}
+
+// lookupUsers returns users by their IDs from the directory.
+// Users which aren't found are left out, so that callers could fall back to their IDs.
+func (c *Client) lookupUsers(userIDs []string) map[string]*model.User {
+	const op = "bot.client.lookupUsers"
+
+	users, err := c.users.users(userIDs)
+	if err != nil {
+		c.l.Error("failed to get users", slog.String("op", op), slog.Int("count", len(userIDs)), sl.Error(err))
+	}
+
+	return users
+}
+
+// usernames returns usernames of the users by their IDs.
+// Users which aren't found are left out, so that callers could fall back to their IDs.
+func (c *Client) usernames(userIDs []string) map[string]string {
+	users := c.lookupUsers(userIDs)
+
+	names := make(map[string]string, len(users))
+	for id, user := range users {
+		names[id] = user.Username
+	}
+
+	return names
+}
+
+// username returns username of the user, or user ID in case user isn't found.
+func (c *Client) username(userID string) string {
+	if user, ok := c.lookupUsers([]string{userID})[userID]; ok {
+		return user.Username
+	}
+
+	return userID
+}
+
+// displayName returns name of the user shown in Mattermost, or user ID in case user isn't found.
+func (c *Client) displayName(userID string) string {
+	if user, ok := c.lookupUsers([]string{userID})[userID]; ok {
+		return user.GetDisplayName(model.ShowNicknameFullName)
+	}
+
+	return userID
+}
+
+// handleUserUpdated drops updated user from the directory, e.g. after it's renamed.
+func (c *Client) handleUserUpdated(log *slog.Logger, event *model.WebSocketEvent) {
+	// Websocket client decodes user of the event, but it's a map in case event is built otherwise.
+	var id string
+	switch user := event.GetData()["user"].(type) {
+	case *model.User:
+		id = user.Id
+	case map[string]any:
+		id, _ = user["id"].(string)
+	}
+	if id == "" {
+		log.Error("user_updated event without user id")
+		return
+	}
+
+	log.Debug("user updated, dropping it from cache", slog.String("user_id", id))
+	c.users.invalidate(id)
+}
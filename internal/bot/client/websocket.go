@@ -0,0 +1,322 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"vote-bot/internal/config"
+	"vote-bot/pkg/backoff"
+	"vote-bot/pkg/sl"
+
+	"github.com/gorilla/websocket"
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+var (
+	errConnectionClosed = errors.New("connection closed by server")
+	errPingTimeout      = errors.New("no ping from server")
+	errPongTimeout      = errors.New("no pong from server")
+)
+
+// ConnState is a state of websocket connection.
+type ConnState string
+
+const (
+	// ConnStateDisabled means websocket is turned off in config.
+	ConnStateDisabled     ConnState = "disabled"
+	ConnStateConnecting   ConnState = "connecting"
+	ConnStateConnected    ConnState = "connected"
+	ConnStateDisconnected ConnState = "disconnected"
+	ConnStateStopped      ConnState = "stopped"
+)
+
+// Health describes websocket connection for health checks.
+type Health struct {
+	State ConnState
+	// Since is when the connection got into the state.
+	Since time.Time
+	// LastError is a reason of the last disconnect or failed connect.
+	LastError string
+	// Reconnects is a number of successful connects after the first one.
+	Reconnects uint64
+}
+
+// Healthy reports whether the bot receives events, or it's not supposed to.
+func (h Health) Healthy() bool {
+	return h.State == ConnStateConnected || h.State == ConnStateDisabled
+}
+
+// connHealth keeps health of the connection shared between listener and health checks.
+type connHealth struct {
+	mu        sync.Mutex
+	health    Health
+	connected bool
+}
+
+func (h *connHealth) set(state ConnState, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if state == ConnStateConnected {
+		if h.connected {
+			h.health.Reconnects++
+		}
+		h.connected = true
+	}
+	if err != nil {
+		h.health.LastError = err.Error()
+	}
+	h.health.State = state
+	h.health.Since = time.Now()
+}
+
+func (h *connHealth) get() Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.health
+}
+
+// Health returns state of websocket connection.
+func (c *Client) Health() Health {
+	return c.health.get()
+}
+
+// listenToEvents connects to the Mattermost WebSocket API and listens for user events (messages).
+// Posts are handled by event queue, so that slow command in one channel doesn't block the others.
+//
+// Connection is reopened with growing delays until StopListening is called.
+func (c *Client) listenToEvents() {
+	const op = "bot.client.listenToEvents"
+
+	log := c.l.With(slog.String("op", op))
+
+	retry := backoff.New(c.config.ReconnectMinDelay, c.config.ReconnectMaxDelay)
+	for !c.stopped() {
+		c.health.set(ConnStateConnecting, nil)
+		log.Debug("connecting to mattermost websocket...", slog.String("url", c.websocketURL))
+
+		ws, err := c.connectWebSocket()
+		if err != nil {
+			c.health.set(ConnStateDisconnected, err)
+
+			delay := retry.Next()
+			log.Warn(
+				"failed to connect to mattermost websocket, retrying",
+				slog.Uint64("attempt", uint64(retry.Attempt())),
+				slog.Duration("delay", delay),
+				sl.Error(err),
+			)
+			c.sleep(delay)
+			continue
+		}
+
+		connectedAt := time.Now()
+		c.health.set(ConnStateConnected, nil)
+		log.Info("mattermost websocket connected")
+
+		err = c.listen(log, ws)
+		if c.stopped() {
+			break
+		}
+		c.health.set(ConnStateDisconnected, err)
+
+		// Connection which lived long enough is considered stable,
+		// so reconnecting after it starts with the min delay.
+		if time.Since(connectedAt) >= c.config.ReconnectMaxDelay {
+			retry.Reset()
+		}
+
+		delay := retry.Next()
+		log.Warn("mattermost websocket disconnected, reconnecting", slog.Duration("delay", delay), sl.Error(err))
+		c.sleep(delay)
+	}
+
+	c.health.set(ConnStateStopped, nil)
+	log.Info("stopped listening to mattermost websocket")
+}
+
+func (c *Client) connectWebSocket() (*model.WebSocketClient, error) {
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = c.tlsConfig
+
+	ws, err := model.NewWebSocketClient4WithDialer(&dialer, c.websocketURL, c.mattermostClient.AuthToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// listen submits events of the connection until it's dead or StopListening is called.
+// It returns reason of the disconnect.
+func (c *Client) listen(log *slog.Logger, ws *model.WebSocketClient) error {
+	pongs := make(chan struct{}, 1)
+	// Pong handler must be set before reading is started by Listen.
+	ws.Conn.SetPongHandler(func(string) error {
+		select {
+		case pongs <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	ws.Listen()
+	defer closeWebSocket(ws)
+
+	dead := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.keepAlive(ws, pongs, dead, stop)
+
+	// Posts missed while disconnected are queued before the new ones,
+	// which wait in the websocket client meanwhile.
+	c.replayMissed(log)
+
+	// Responses aren't used, but they must be read to not block the reader.
+	responses := ws.ResponseChannel
+	for {
+		select {
+		case event, ok := <-ws.EventChannel:
+			if !ok {
+				if ws.ListenError != nil {
+					return ws.ListenError
+				}
+				return errConnectionClosed
+			}
+			c.submitEvent(log, event)
+		case _, ok := <-responses:
+			if !ok {
+				responses = nil
+			}
+		case <-ws.PingTimeoutChannel:
+			return errPingTimeout
+		case err := <-dead:
+			return err
+		case <-c.done:
+			return nil
+		}
+	}
+}
+
+// keepAlive pings the server and reports to dead in case pong isn't received in time.
+func (c *Client) keepAlive(ws *model.WebSocketClient, pongs <-chan struct{}, dead chan<- error, stop <-chan struct{}) {
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		// Pongs aren't read while reader waits for full event queue,
+		// so connection isn't considered dead in that case.
+		if len(ws.EventChannel) == cap(ws.EventChannel) {
+			continue
+		}
+
+		err := ws.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.config.PongTimeout))
+		if err != nil {
+			dead <- fmt.Errorf("failed to send ping: %w", err)
+			return
+		}
+
+		timer := time.NewTimer(c.config.PongTimeout)
+		select {
+		case <-pongs:
+			timer.Stop()
+		case <-timer.C:
+			dead <- errPongTimeout
+			return
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// closeWebSocket closes the connection and drops events left in it,
+// so that reader of the websocket client isn't blocked forever.
+func closeWebSocket(ws *model.WebSocketClient) {
+	ws.Close()
+
+	go func() {
+		for range ws.EventChannel {
+		}
+	}()
+	go func() {
+		for range ws.ResponseChannel {
+		}
+	}()
+}
+
+// sleep waits for d or until StopListening is called.
+func (c *Client) sleep(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-c.done:
+	}
+}
+
+// websocketURL returns URL of Mattermost websocket with scheme matching the server one.
+func websocketURL(server *url.URL) (string, error) {
+	const op = "bot.client.websocketURL"
+
+	u := *server
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("%s: unsupported scheme %q of mattermost server url", op, u.Scheme)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return u.String(), nil
+}
+
+// newTLSConfig returns TLS config of connections to Mattermost.
+// Certificates from CAFile are trusted in addition to the system ones.
+func newTLSConfig(cfg config.Mattermost) (*tls.Config, error) {
+	const op = "bot.client.newTLSConfig"
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to read ca file: %w", op, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found in ca file %s", op, cfg.CAFile)
+	}
+	tlsConfig.RootCAs = pool
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"vote-bot/internal/bot/client"
+)
+
+// HealthPath is an endpoint of health checks.
+const HealthPath = "/healthz"
+
+type healthResponse struct {
+	Status    string            `json:"status"`
+	Replica   replicaResponse   `json:"replica"`
+	WebSocket websocketResponse `json:"websocket"`
+}
+
+type replicaResponse struct {
+	ID string `json:"id"`
+	// Role is leader or standby.
+	Role  string    `json:"role"`
+	Since time.Time `json:"since"`
+}
+
+type websocketResponse struct {
+	State      client.ConnState `json:"state"`
+	Since      time.Time        `json:"since"`
+	LastError  string           `json:"last_error,omitempty"`
+	Reconnects uint64           `json:"reconnects"`
+}
+
+// handleHealth responds with role of the replica and state of websocket connection.
+// Status is 503 while websocket of the leader is enabled but not connected.
+// Standby doesn't listen to websocket, so it's always healthy.
+func (b *Bot) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	health := b.Client.Health()
+	replica := b.Elector.Status()
+
+	role := "standby"
+	if replica.IsLeader {
+		role = "leader"
+	}
+
+	resp := healthResponse{
+		Status: "ok",
+		Replica: replicaResponse{
+			ID:    replica.ID,
+			Role:  role,
+			Since: replica.Since.UTC(),
+		},
+		WebSocket: websocketResponse{
+			State:      health.State,
+			Since:      health.Since.UTC(),
+			LastError:  health.LastError,
+			Reconnects: health.Reconnects,
+		},
+	}
+	status := http.StatusOK
+	if replica.IsLeader && !health.Healthy() {
+		resp.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
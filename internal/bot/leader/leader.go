@@ -0,0 +1,241 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+	"vote-bot/internal/config"
+	"vote-bot/pkg/sl"
+)
+
+// LeaseName is a name of the lease held by the leader.
+const LeaseName = "leader"
+
+type LeaseService interface {
+	Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name string, holder string) error
+}
+
+// Status describes the replica for logs and health checks.
+type Status struct {
+	ID       string
+	IsLeader bool
+	// Since is when the replica became leader or standby.
+	Since time.Time
+}
+
+// Elector makes one of bot replicas the leader by lease in repo.
+//
+// The leader renews the lease every RenewInterval, standbys try to take it at the same rate,
+// so they take over within LeaseTTL after the leader dies.
+type Elector struct {
+	log    *slog.Logger
+	leases LeaseService
+	id     string
+	ttl    time.Duration
+	renew  time.Duration
+	// timeout limits one request to the repo.
+	timeout time.Duration
+
+	mu        sync.Mutex
+	status    Status
+	onElected func()
+	onDemoted func()
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func New(log *slog.Logger, leases LeaseService, cfg config.Leader, timeout time.Duration) (*Elector, error) {
+	const op = "bot.leader.New"
+
+	if cfg.RenewInterval >= cfg.LeaseTTL {
+		return nil, fmt.Errorf("%s: renew interval must be less than lease ttl", op)
+	}
+
+	id := cfg.ID
+	if id == "" {
+		var err error
+		id, err = replicaID()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return &Elector{
+		log:     log.With(slog.String("replica_id", id)),
+		leases:  leases,
+		id:      id,
+		ttl:     cfg.LeaseTTL,
+		renew:   cfg.RenewInterval,
+		timeout: timeout,
+		status:  Status{ID: id, Since: time.Now()},
+	}, nil
+}
+
+// Start runs election. onElected is called when the replica becomes leader,
+// onDemoted when it loses leadership. Both are called from one goroutine.
+func (e *Elector) Start(onElected, onDemoted func()) {
+	const op = "bot.leader.Start"
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	e.onElected = onElected
+	e.onDemoted = onDemoted
+
+	go e.run(ctx, e.done)
+
+	e.log.Info("started leader election", slog.String("op", op), slog.Duration("lease_ttl", e.ttl))
+}
+
+// Stop stops election. The leader steps down and releases the lease,
+// so that a standby takes over without waiting for it to expire.
+func (e *Elector) Stop() {
+	const op = "bot.leader.Stop"
+
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel = nil
+	e.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+
+	if !e.Status().IsLeader {
+		return
+	}
+
+	e.demote("replica is stopping")
+
+	ctx, cancelRelease := context.WithTimeout(context.Background(), e.timeout)
+	defer cancelRelease()
+
+	if err := e.leases.Release(ctx, LeaseName, e.id); err != nil {
+		e.log.Error("failed to release leadership", slog.String("op", op), sl.Error(err))
+		return
+	}
+
+	e.log.Info("released leadership", slog.String("op", op))
+}
+
+// Status returns current role of the replica.
+func (e *Elector) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.status
+}
+
+func (e *Elector) run(ctx context.Context, done chan struct{}) {
+	const op = "bot.leader.run"
+
+	defer close(done)
+
+	log := e.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(e.renew)
+	defer ticker.Stop()
+
+	var renewedAt time.Time
+	for {
+		// Lease expires ttl after the request, so time is taken before it.
+		requestedAt := time.Now()
+		acquired, err := e.acquire(ctx)
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			log.Error("failed to acquire leadership", sl.Error(err))
+
+			// Leader keeps leading while its lease may still be valid.
+			if e.Status().IsLeader && time.Since(renewedAt) >= e.ttl {
+				e.demote("lease expired")
+			}
+		case acquired:
+			renewedAt = requestedAt
+			if !e.Status().IsLeader {
+				e.elect()
+			}
+		default:
+			if e.Status().IsLeader {
+				e.demote("lease was taken by another replica")
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Elector) acquire(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	return e.leases.Acquire(ctx, LeaseName, e.id, e.ttl)
+}
+
+func (e *Elector) elect() {
+	e.mu.Lock()
+	e.status.IsLeader = true
+	e.status.Since = time.Now()
+	onElected := e.onElected
+	e.mu.Unlock()
+
+	e.log.Info("replica became leader")
+
+	if onElected != nil {
+		onElected()
+	}
+}
+
+func (e *Elector) demote(reason string) {
+	e.mu.Lock()
+	e.status.IsLeader = false
+	e.status.Since = time.Now()
+	onDemoted := e.onDemoted
+	e.mu.Unlock()
+
+	e.log.Warn("replica is standby now", slog.String("reason", reason))
+
+	if onDemoted != nil {
+		onDemoted()
+	}
+}
+
+// replicaID returns hostname with random suffix,
+// so that replicas on the same host get different IDs.
+func replicaID() (string, error) {
+	const op = "bot.leader.replicaID"
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to get hostname: %w", op, err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("%s: failed to generate id: %w", op, err)
+	}
+
+	return host + "-" + hex.EncodeToString(suffix), nil
+}
@@ -0,0 +1,429 @@
+// Package parser parses bot commands, e.g. `!create_poll --closes 2h "Lunch?" Pizza Sushi`,
+// and forms their help from the same specs.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrNotCommand        = errors.New("message is not a command")
+	ErrUnknownCommand    = errors.New("unknown command")
+	ErrUnterminatedQuote = errors.New("unterminated quote")
+	ErrUnknownFlag       = errors.New("unknown flag")
+	ErrMissingArgument   = errors.New("missing argument")
+	ErrTooManyArguments  = errors.New("too many arguments")
+	ErrInvalidValue      = errors.New("invalid value")
+)
+
+// Error is an error of parsing command which can be shown to the user.
+type Error struct {
+	// Command is a name of the command, it's empty in case command is unknown.
+	Command string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Kind is a type of argument or flag value.
+type Kind int
+
+const (
+	KindString Kind = iota
+	// KindText is a string which may be given without quotes
+	// in case it's the last argument on the line, e.g. name of a poll.
+	KindText
+	KindUint
+	// KindDeadline is either duration from now (e.g. "2h30m") or time in UTC ("2006-01-02T15:04").
+	KindDeadline
+	// KindBool is a flag without value.
+	KindBool
+)
+
+// Arg is a positional argument of command.
+type Arg struct {
+	Name  string
+	Kind  Kind
+	Usage string
+	// Optional args may be omitted, they must follow the required ones.
+	Optional bool
+	// Variadic arg takes all the remaining values, it must be the last one.
+	Variadic bool
+	// PerLine variadic arg takes lines following the first one as is, one value per line,
+	// in case they are given. So values may contain spaces without quotes.
+	PerLine bool
+	// Values limit string argument to one of them, e.g. status of polls.
+	Values []string
+}
+
+// Flag is an optional named argument of command, e.g. "--closes 2h".
+type Flag struct {
+	// Name is given without dashes.
+	Name string
+	Kind Kind
+	// Value is a name of the value in help, e.g. "deadline".
+	Value string
+	Usage string
+}
+
+// Command is a spec of command used both to parse and to describe it.
+type Command struct {
+	// Name is given without prefix.
+	Name    string
+	Summary string
+	// Description is shown in help of the command after the summary.
+	Description string
+	Args        []Arg
+	Flags       []Flag
+	Examples    []string
+}
+
+func (c *Command) flag(name string) (Flag, bool) {
+	for _, f := range c.Flags {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return Flag{}, false
+}
+
+// Registry keeps specs of all commands, which start with common prefix, e.g. "!".
+type Registry struct {
+	prefix   string
+	commands []*Command
+	byName   map[string]*Command
+	now      func() time.Time
+}
+
+// NewRegistry registers commands. It panics on invalid specs, since they are defined in code.
+func NewRegistry(prefix string, commands ...Command) *Registry {
+	r := &Registry{
+		prefix: prefix,
+		byName: make(map[string]*Command),
+		now:    time.Now,
+	}
+
+	for i := range commands {
+		cmd := &commands[i]
+		validate(cmd)
+
+		if _, ok := r.byName[cmd.Name]; ok {
+			panic(fmt.Sprintf("parser: command %q is registered twice", cmd.Name))
+		}
+		r.byName[cmd.Name] = cmd
+		r.commands = append(r.commands, cmd)
+	}
+
+	return r
+}
+
+func validate(cmd *Command) {
+	optional := false
+	for i, arg := range cmd.Args {
+		switch {
+		case arg.Kind == KindBool:
+			panic(fmt.Sprintf("parser: argument %q of command %q can't be bool", arg.Name, cmd.Name))
+		case arg.Variadic && i != len(cmd.Args)-1:
+			panic(fmt.Sprintf("parser: variadic argument %q of command %q isn't the last one", arg.Name, cmd.Name))
+		case arg.PerLine && !arg.Variadic:
+			panic(fmt.Sprintf("parser: per line argument %q of command %q isn't variadic", arg.Name, cmd.Name))
+		case len(arg.Values) != 0 && (arg.Kind != KindString || arg.Variadic):
+			panic(fmt.Sprintf("parser: argument %q of command %q with values must be single string", arg.Name, cmd.Name))
+		case optional && !arg.Optional:
+			panic(fmt.Sprintf("parser: required argument %q of command %q follows optional one", arg.Name, cmd.Name))
+		}
+		optional = arg.Optional
+	}
+}
+
+// Prefix returns prefix of commands.
+func (r *Registry) Prefix() string {
+	return r.prefix
+}
+
+// Commands returns specs of commands in order they were registered.
+func (r *Registry) Commands() []Command {
+	commands := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		commands = append(commands, *cmd)
+	}
+
+	return commands
+}
+
+// Lookup finds command by its name, given with or without prefix.
+func (r *Registry) Lookup(name string) (Command, bool) {
+	cmd, ok := r.byName[strings.TrimPrefix(name, r.prefix)]
+	if !ok {
+		return Command{}, false
+	}
+
+	return *cmd, true
+}
+
+// Call is a parsed command with values of its arguments and flags.
+type Call struct {
+	// Command is a name of the called command without prefix.
+	Command string
+	values  map[string]any
+}
+
+// Has reports whether argument or flag was given.
+func (c *Call) Has(name string) bool {
+	_, ok := c.values[name]
+	return ok
+}
+
+// String returns value of string argument or flag, it's empty if it wasn't given.
+func (c *Call) String(name string) string {
+	v, _ := c.values[name].(string)
+	return v
+}
+
+// Strings returns values of variadic argument.
+func (c *Call) Strings(name string) []string {
+	v, _ := c.values[name].([]string)
+	return v
+}
+
+// Uint returns value of uint argument or flag, it's 0 if it wasn't given.
+func (c *Call) Uint(name string) uint64 {
+	v, _ := c.values[name].(uint64)
+	return v
+}
+
+// Time returns value of deadline argument or flag, it's zero if it wasn't given.
+func (c *Call) Time(name string) time.Time {
+	v, _ := c.values[name].(time.Time)
+	return v
+}
+
+// Bool reports whether bool flag was given.
+func (c *Call) Bool(name string) bool {
+	v, _ := c.values[name].(bool)
+	return v
+}
+
+// Parse parses message with command.
+//
+// Arguments are given on the first line, flags may be mixed with them, and "--" ends flags.
+// Lines following the first one continue it, except for commands with per line argument,
+// which takes these lines as values. In that case the last argument on the first line
+// may be text without quotes:
+//
+//	!create_poll Where to go?
+//	Cinema
+//	Theatre
+//
+// It returns ErrNotCommand for messages without prefix and *Error in case command is invalid.
+func (r *Registry) Parse(msg string) (*Call, error) {
+	msg = strings.TrimSpace(msg)
+	if !strings.HasPrefix(msg, r.prefix) {
+		return nil, ErrNotCommand
+	}
+
+	lines := strings.Split(msg, "\n")
+	// Name is cut before tokenizing, so that it's known even if arguments are invalid.
+	name, rest := strings.TrimSpace(lines[0]), ""
+	if i := strings.IndexFunc(name, unicode.IsSpace); i != -1 {
+		name, rest = name[:i], name[i:]
+	}
+	name = strings.TrimPrefix(name, r.prefix)
+
+	cmd, ok := r.byName[name]
+	if !ok {
+		return nil, &Error{Err: fmt.Errorf("%w %s%s", ErrUnknownCommand, r.prefix, name)}
+	}
+
+	first, err := tokenize(rest)
+	if err != nil {
+		return nil, &Error{Command: cmd.Name, Err: err}
+	}
+
+	values, err := r.parseArgs(cmd, first, lines[1:])
+	if err != nil {
+		return nil, &Error{Command: cmd.Name, Err: err}
+	}
+
+	return &Call{Command: cmd.Name, values: values}, nil
+}
+
+func (r *Registry) parseArgs(cmd *Command, first []token, rest []string) (map[string]any, error) {
+	var following []string
+	for _, line := range rest {
+		if line = strings.TrimSpace(line); line != "" {
+			following = append(following, line)
+		}
+	}
+
+	fixed := cmd.Args
+	var variadic *Arg
+	if n := len(cmd.Args); n != 0 && cmd.Args[n-1].Variadic {
+		fixed, variadic = cmd.Args[:n-1], &cmd.Args[n-1]
+	}
+
+	perLine := variadic != nil && variadic.PerLine && len(following) != 0
+	tokens := first
+	if !perLine {
+		for _, line := range following {
+			lineTokens, err := tokenize(line)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, lineTokens...)
+		}
+	}
+
+	values := make(map[string]any)
+	positional, err := r.parseFlags(cmd, tokens, values)
+	if err != nil {
+		return nil, err
+	}
+
+	// Text may take the rest of the line in case nothing else is expected on it.
+	if n := len(fixed); n != 0 && fixed[n-1].Kind == KindText && len(positional) > n && (variadic == nil || perLine) {
+		text := strings.Join(positional[n-1:], " ")
+		positional = append(positional[:n-1], text)
+	}
+
+	for _, arg := range fixed {
+		if len(positional) == 0 {
+			if arg.Optional {
+				break
+			}
+			return nil, fmt.Errorf("%w <%s>", ErrMissingArgument, arg.Name)
+		}
+
+		value, err := r.parseValue(arg.Kind, positional[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w of <%s>: %w", ErrInvalidValue, arg.Name, err)
+		}
+		if len(arg.Values) != 0 && !slices.Contains(arg.Values, positional[0]) {
+			return nil, fmt.Errorf("%w of <%s>: %q isn't one of %s", ErrInvalidValue, arg.Name, positional[0], strings.Join(arg.Values, ", "))
+		}
+		values[arg.Name] = value
+		positional = positional[1:]
+	}
+
+	if variadic == nil {
+		if len(positional) != 0 {
+			return nil, fmt.Errorf("%w, unexpected %q", ErrTooManyArguments, positional[0])
+		}
+		return values, nil
+	}
+
+	if perLine {
+		if len(positional) != 0 {
+			return nil, fmt.Errorf("%w, unexpected %q", ErrTooManyArguments, positional[0])
+		}
+		positional = following
+	}
+
+	if len(positional) == 0 {
+		if variadic.Optional {
+			return values, nil
+		}
+		return nil, fmt.Errorf("%w <%s>", ErrMissingArgument, variadic.Name)
+	}
+
+	for _, value := range positional {
+		if _, err := r.parseValue(variadic.Kind, value); err != nil {
+			return nil, fmt.Errorf("%w of <%s>: %w", ErrInvalidValue, variadic.Name, err)
+		}
+	}
+	values[variadic.Name] = positional
+
+	return values, nil
+}
+
+// parseFlags puts values of flags into values and returns positional arguments.
+func (r *Registry) parseFlags(cmd *Command, tokens []token, values map[string]any) ([]string, error) {
+	positional := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.quoted || !strings.HasPrefix(tok.value, "--") {
+			positional = append(positional, tok.value)
+			continue
+		}
+
+		if tok.value == "--" {
+			for _, tok := range tokens[i+1:] {
+				positional = append(positional, tok.value)
+			}
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(tok.value, "--"), "=")
+		flag, ok := cmd.flag(name)
+		if !ok {
+			return nil, fmt.Errorf("%w --%s", ErrUnknownFlag, name)
+		}
+
+		if flag.Kind == KindBool {
+			if hasValue {
+				return nil, fmt.Errorf("%w of --%s: flag doesn't take a value", ErrInvalidValue, name)
+			}
+			values[flag.Name] = true
+			continue
+		}
+
+		if !hasValue {
+			if i+1 == len(tokens) {
+				return nil, fmt.Errorf("%w of --%s: value is missing", ErrInvalidValue, name)
+			}
+			i++
+			value = tokens[i].value
+		}
+
+		parsed, err := r.parseValue(flag.Kind, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w of --%s: %w", ErrInvalidValue, name, err)
+		}
+		values[flag.Name] = parsed
+	}
+
+	return positional, nil
+}
+
+func (r *Registry) parseValue(kind Kind, value string) (any, error) {
+	switch kind {
+	case KindUint:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return n, nil
+	case KindDeadline:
+		return parseDeadline(value, r.now())
+	default:
+		return value, nil
+	}
+}
+
+// parseDeadline parses either duration from now (e.g. "2h30m") or time in UTC ("2006-01-02T15:04").
+func parseDeadline(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%q is neither duration like 2h30m nor time like 2006-01-02T15:04", value)
+}
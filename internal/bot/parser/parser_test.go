@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestRegistry() *Registry {
+	r := NewRegistry("!",
+		Command{
+			Name: "create",
+			Args: []Arg{
+				{Name: "name", Kind: KindText},
+				{Name: "options", Kind: KindString, Variadic: true, PerLine: true},
+			},
+			Flags: []Flag{
+				{Name: "anonymous", Kind: KindBool},
+				{Name: "closes", Kind: KindDeadline, Value: "deadline"},
+				{Name: "max", Kind: KindUint, Value: "n"},
+			},
+		},
+		Command{
+			Name: "vote",
+			Args: []Arg{
+				{Name: "poll_id", Kind: KindUint},
+				{Name: "choice", Kind: KindString, Variadic: true},
+			},
+		},
+		Command{
+			Name: "list",
+			Args: []Arg{{Name: "status", Kind: KindString, Optional: true, Values: []string{"open", "finished"}}},
+		},
+		Command{
+			Name: "rename",
+			Args: []Arg{{Name: "poll_id", Kind: KindUint}, {Name: "name", Kind: KindText}},
+		},
+	)
+	r.now = func() time.Time { return testNow }
+
+	return r
+}
+
+func TestRegistry_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		want    map[string]any
+		wantErr error
+	}{
+		{
+			name: "quoted arguments with spaces",
+			msg:  `!create "Where to go?" 'Cinema hall' Theatre`,
+			want: map[string]any{"name": "Where to go?", "options": []string{"Cinema hall", "Theatre"}},
+		},
+		{
+			name: "escaped quotes inside double quotes",
+			msg:  `!create "Say \"hi\"" a`,
+			want: map[string]any{"name": `Say "hi"`, "options": []string{"a"}},
+		},
+		{
+			name: "quote in the middle of word",
+			msg:  `!create Lunch? Don't Do`,
+			want: map[string]any{"name": "Lunch?", "options": []string{"Don't", "Do"}},
+		},
+		{
+			name: "options per line with name without quotes",
+			msg:  "!create Where to go on Friday?\nCinema hall\n\n  Theatre  ",
+			want: map[string]any{"name": "Where to go on Friday?", "options": []string{"Cinema hall", "Theatre"}},
+		},
+		{
+			name: "flags mixed with arguments",
+			msg:  "!create --anonymous Lunch --closes=2h a --max 2 b",
+			want: map[string]any{
+				"name": "Lunch", "options": []string{"a", "b"},
+				"anonymous": true, "closes": testNow.Add(2 * time.Hour), "max": uint64(2),
+			},
+		},
+		{
+			name: "quoted flag and flags after double dash are arguments",
+			msg:  `!create "--anonymous" -- --max`,
+			want: map[string]any{"name": "--anonymous", "options": []string{"--max"}},
+		},
+		{
+			name: "deadline as time",
+			msg:  "!create --closes 2025-02-03T10:30 Lunch a",
+			want: map[string]any{"name": "Lunch", "options": []string{"a"}, "closes": time.Date(2025, 2, 3, 10, 30, 0, 0, time.UTC)},
+		},
+		{
+			name: "text takes the rest of the line",
+			msg:  "!rename 5 Where to go?",
+			want: map[string]any{"poll_id": uint64(5), "name": "Where to go?"},
+		},
+		{
+			name: "optional argument omitted",
+			msg:  "!list",
+			want: map[string]any{},
+		},
+		{
+			name: "optional argument given",
+			msg:  "!list finished",
+			want: map[string]any{"status": "finished"},
+		},
+		{
+			name:    "argument not in values",
+			msg:     "!list closed",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "unterminated double quote",
+			msg:     `!create "Where to go? a b`,
+			wantErr: ErrUnterminatedQuote,
+		},
+		{
+			name:    "unterminated single quote on the next line",
+			msg:     "!vote 5\n'1 2",
+			wantErr: ErrUnterminatedQuote,
+		},
+		{
+			name:    "unknown flag",
+			msg:     "!create --secret Lunch a",
+			wantErr: ErrUnknownFlag,
+		},
+		{
+			name:    "flag without value",
+			msg:     "!create Lunch a --closes",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "bool flag with value",
+			msg:     "!create --anonymous=yes Lunch a",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "invalid deadline",
+			msg:     "!create --closes tomorrow Lunch a",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "missing required argument",
+			msg:     "!rename 5",
+			wantErr: ErrMissingArgument,
+		},
+		{
+			name:    "missing variadic argument",
+			msg:     "!vote 5",
+			wantErr: ErrMissingArgument,
+		},
+		{
+			name:    "not a number",
+			msg:     "!vote five 1",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "too many arguments",
+			msg:     "!list open finished",
+			wantErr: ErrTooManyArguments,
+		},
+		{
+			name:    "unknown command",
+			msg:     "!unknown 5",
+			wantErr: ErrUnknownCommand,
+		},
+		{
+			name:    "not a command",
+			msg:     "hello !vote 5 1",
+			wantErr: ErrNotCommand,
+		},
+	}
+
+	r := newTestRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := r.Parse(tt.msg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var parseErr *Error
+				if tt.wantErr != ErrNotCommand && !errors.As(err, &parseErr) {
+					t.Errorf("Parse() error = %T, want *Error", err)
+				}
+				return
+			}
+
+			if len(call.values) != len(tt.want) {
+				t.Errorf("Parse() values = %v, want %v", call.values, tt.want)
+			}
+			for name, want := range tt.want {
+				got := call.values[name]
+				switch want := want.(type) {
+				case []string:
+					if !slices.Equal(call.Strings(name), want) {
+						t.Errorf("Parse() %s = %q, want %q", name, got, want)
+					}
+				case time.Time:
+					if !call.Time(name).Equal(want) {
+						t.Errorf("Parse() %s = %v, want %v", name, got, want)
+					}
+				default:
+					if got != want {
+						t.Errorf("Parse() %s = %v, want %v", name, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_ParseErrorCommand(t *testing.T) {
+	_, err := newTestRegistry().Parse("!rename 5")
+
+	var parseErr *Error
+	if !errors.As(err, &parseErr) || parseErr.Command != "rename" {
+		t.Errorf("Parse() error = %v, want *Error of command rename", err)
+	}
+}
+
+func TestNewRegistry_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewRegistry() with duplicate command didn't panic")
+		}
+	}()
+
+	NewRegistry("!", Command{Name: "vote"}, Command{Name: "vote"})
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// token is a word of the command line.
+type token struct {
+	value string
+	// quoted tokens are never treated as flags.
+	quoted bool
+}
+
+// tokenize splits line into words separated by spaces.
+//
+// Word which starts with a double or single quote lasts until the closing one,
+// so it may contain spaces, e.g. "Where to go?". Inside double quotes \" and \\
+// are unescaped. Quotes in the middle of a word are kept as is, e.g. Don't.
+func tokenize(line string) ([]token, error) {
+	var (
+		tokens []token
+		word   strings.Builder
+	)
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		quote := runes[i]
+		if quote != '"' && quote != '\'' {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{value: string(runes[start:i])})
+			continue
+		}
+
+		word.Reset()
+		closed := false
+		for i++; i < len(runes); i++ {
+			r := runes[i]
+			if r == quote {
+				closed = true
+				i++
+				break
+			}
+			if quote == '"' && r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\') {
+				i++
+				r = runes[i]
+			}
+			word.WriteRune(r)
+		}
+		if !closed {
+			return nil, ErrUnterminatedQuote
+		}
+		tokens = append(tokens, token{value: word.String(), quoted: true})
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Synopsis returns one line usage of command, e.g. "!vote <poll_id> <choice>...".
+func (r *Registry) Synopsis(cmd Command) string {
+	var b strings.Builder
+
+	b.WriteString(r.prefix + cmd.Name)
+	if len(cmd.Flags) != 0 {
+		b.WriteString(" [flags]")
+	}
+	for _, arg := range cmd.Args {
+		placeholder := "<" + arg.Name + ">"
+		if len(arg.Values) != 0 {
+			placeholder = strings.Join(arg.Values, "|")
+		}
+		if arg.Variadic {
+			placeholder += "..."
+		}
+		if arg.Optional {
+			placeholder = "[" + placeholder + "]"
+		}
+		b.WriteString(" " + placeholder)
+	}
+
+	return b.String()
+}
+
+// Usage returns help of command in markdown: synopsis, description,
+// arguments, flags and examples.
+func (r *Registry) Usage(cmd Command) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "`%s`\n%s", r.Synopsis(cmd), cmd.Summary)
+	if cmd.Description != "" {
+		b.WriteString("\n" + cmd.Description)
+	}
+
+	if len(cmd.Args) != 0 {
+		b.WriteString("\n\nArguments:")
+		for _, arg := range cmd.Args {
+			fmt.Fprintf(&b, "\n- `<%s>` %s", arg.Name, arg.Usage)
+		}
+	}
+
+	if len(cmd.Flags) != 0 {
+		b.WriteString("\n\nFlags:")
+		for _, flag := range cmd.Flags {
+			name := "--" + flag.Name
+			if flag.Kind != KindBool {
+				name += " <" + flag.Value + ">"
+			}
+			fmt.Fprintf(&b, "\n- `%s` %s", name, flag.Usage)
+		}
+	}
+
+	if len(cmd.Examples) != 0 {
+		b.WriteString("\n\nExamples:\n```\n" + strings.Join(cmd.Examples, "\n\n") + "\n```")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,234 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/service"
+	"vote-bot/pkg/sl"
+)
+
+// retryDelay is a delay before the next attempt to finish poll
+// in case the previous one failed.
+const retryDelay = time.Minute
+
+// syncInterval is how often pending polls are reloaded from repo,
+// since they may be created via other bot replicas.
+const syncInterval = time.Minute
+
+type PollService interface {
+	GetPendingPolls(ctx context.Context) ([]entity.Poll, error)
+	FinishPoll(ctx context.Context, pollID uint64, user string, channel string) error
+}
+
+// Scheduler finishes polls when their deadline passes.
+type Scheduler struct {
+	log   *slog.Logger
+	polls PollService
+	// timeout limits finishing of one poll.
+	timeout time.Duration
+
+	mu       sync.Mutex
+	timers   map[uint64]*time.Timer
+	started  bool
+	onFinish func(ctx context.Context, poll entity.Poll)
+	// ctx is canceled on Stop to interrupt polls being finished.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func New(log *slog.Logger, polls PollService, timeout time.Duration) *Scheduler {
+	return &Scheduler{
+		log:     log,
+		polls:   polls,
+		timeout: timeout,
+		timers:  make(map[uint64]*time.Timer),
+	}
+}
+
+// Start starts finishing polls. Deadlines of pending polls are restored from repo
+// right away and then every syncInterval. onFinish is called after poll was finished by scheduler.
+//
+// Polls scheduled before Start are finished only after it is called.
+func (s *Scheduler) Start(onFinish func(ctx context.Context, poll entity.Poll)) {
+	const op = "bot.scheduler.Start"
+
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	s.onFinish = onFinish
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	go s.syncPolls(ctx)
+
+	s.log.Info("scheduler started", slog.String("op", op))
+}
+
+// Schedule sets timer which finishes poll at poll.ClosesAt.
+// Previous timer of the poll is replaced, so it can be used to change deadline.
+func (s *Scheduler) Schedule(poll entity.Poll) {
+	if poll.ClosesAt.IsZero() || poll.IsFinished {
+		s.Cancel(poll.ID)
+		return
+	}
+
+	s.schedule(poll, true)
+}
+
+// schedule sets timer of the poll. Existing timer is replaced only in case replace is true.
+// It reports whether timer was set.
+func (s *Scheduler) schedule(poll entity.Poll, replace bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return false
+	}
+
+	if timer, ok := s.timers[poll.ID]; ok {
+		if !replace {
+			return false
+		}
+		timer.Stop()
+	}
+
+	s.timers[poll.ID] = time.AfterFunc(time.Until(poll.ClosesAt), func() {
+		s.finish(poll)
+	})
+
+	return true
+}
+
+// syncPolls schedules pending polls from repo until ctx is done.
+func (s *Scheduler) syncPolls(ctx context.Context) {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sync(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sync schedules pending polls which don't have timer yet.
+// Timers of polls retried after failure are kept.
+func (s *Scheduler) sync(ctx context.Context) {
+	const op = "bot.scheduler.sync"
+
+	log := s.log.With(slog.String("op", op))
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	polls, err := s.polls.GetPendingPolls(ctx)
+	if err != nil {
+		// Scheduler was stopped.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return
+		}
+
+		log.Error("failed to get pending polls, retrying later", sl.Error(err))
+		return
+	}
+
+	scheduled := 0
+	for _, poll := range polls {
+		if s.schedule(poll, false) {
+			scheduled++
+		}
+	}
+
+	if scheduled != 0 {
+		log.Info("scheduled pending polls", slog.Int("count", scheduled))
+	}
+}
+
+// Cancel removes timer of the poll, e.g. in case it was finished manually.
+func (s *Scheduler) Cancel(pollID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timer, ok := s.timers[pollID]; ok {
+		timer.Stop()
+		delete(s.timers, pollID)
+	}
+}
+
+// Stop stops all timers and interrupts polls being finished.
+// Polls are finished after the next Start.
+func (s *Scheduler) Stop() {
+	const op = "bot.scheduler.Stop"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for pollID, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, pollID)
+	}
+	s.started = false
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.log.Info("scheduler stopped", slog.String("op", op))
+}
+
+func (s *Scheduler) finish(poll entity.Poll) {
+	const op = "bot.scheduler.finish"
+
+	log := s.log.With(slog.String("op", op), slog.Uint64("poll_id", poll.ID))
+
+	s.mu.Lock()
+	delete(s.timers, poll.ID)
+	onFinish := s.onFinish
+	baseCtx := s.ctx
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(baseCtx, s.timeout)
+	defer cancel()
+
+	// Poll is finished on behalf of its creator.
+	err := s.polls.FinishPoll(ctx, poll.ID, poll.Creator, poll.Channel)
+	if err != nil {
+		// Poll could be deleted or finished manually in the meantime.
+		if errors.Is(err, service.ErrPollNotFound) || errors.Is(err, service.ErrPollFinished) {
+			log.Debug("poll was already finished or deleted", sl.Error(err))
+			return
+		}
+
+		// Scheduler was stopped, poll is finished after the next Start.
+		if baseCtx.Err() != nil {
+			log.Debug("scheduler was stopped before poll was finished", sl.Error(err))
+			return
+		}
+
+		log.Error("failed to finish poll by deadline, retrying later", sl.Error(err))
+		poll.ClosesAt = time.Now().Add(retryDelay)
+		s.Schedule(poll)
+		return
+	}
+
+	log.Info("poll was finished by deadline")
+
+	poll.IsFinished = true
+	if onFinish != nil {
+		notifyCtx, cancel := context.WithTimeout(baseCtx, s.timeout)
+		defer cancel()
+
+		onFinish(notifyCtx, poll)
+	}
+}
@@ -0,0 +1,139 @@
+package workers
+
+import (
+	"expvar"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// metrics of the pool exposed via expvar (/debug/vars).
+var metrics = expvar.NewMap("event_workers")
+
+// Pool runs tasks concurrently in a fixed number of workers.
+//
+// Tasks with the same key are run by the same worker in order they were submitted,
+// so e.g. events of one channel are never handled concurrently.
+type Pool struct {
+	log    *slog.Logger
+	queues []chan func()
+	wg     sync.WaitGroup
+
+	// mu guards queues from being closed while tasks are submitted.
+	mu      sync.RWMutex
+	stopped bool
+
+	processed expvar.Int
+	blocked   expvar.Int
+	rejected  expvar.Int
+}
+
+// New creates pool of workers, each of them has a queue of queueSize tasks.
+func New(log *slog.Logger, workers int, queueSize int) *Pool {
+	workers = max(workers, 1)
+
+	p := &Pool{
+		log:    log,
+		queues: make([]chan func(), workers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+	}
+
+	metrics.Set("workers", expvar.Func(func() any { return len(p.queues) }))
+	metrics.Set("queue_capacity", expvar.Func(func() any { return len(p.queues) * queueSize }))
+	metrics.Set("queue_depth", expvar.Func(func() any { return p.depth() }))
+	metrics.Set("processed", &p.processed)
+	metrics.Set("blocked", &p.blocked)
+	metrics.Set("rejected", &p.rejected)
+
+	return p
+}
+
+// Start runs workers.
+func (p *Pool) Start() {
+	for _, queue := range p.queues {
+		p.wg.Add(1)
+		go p.work(queue)
+	}
+}
+
+// Submit adds task to the queue of the worker which handles the key.
+//
+// In case the queue is full, Submit blocks until there is space in it,
+// which slows down the producer. false is returned in case pool is stopped.
+func (p *Pool) Submit(key string, task func()) bool {
+	const op = "bot.workers.Submit"
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		p.rejected.Add(1)
+		return false
+	}
+
+	queue := p.queues[p.index(key)]
+	select {
+	case queue <- task:
+		return true
+	default:
+	}
+
+	p.blocked.Add(1)
+	p.log.Warn("event queue is full, waiting for workers", slog.String("op", op))
+
+	start := time.Now()
+	queue <- task
+	p.log.Debug("event was queued after waiting", slog.String("op", op), slog.Duration("waited", time.Since(start)))
+
+	return true
+}
+
+// Stop stops accepting new tasks and waits until queued ones are done.
+func (p *Pool) Stop() {
+	const op = "bot.workers.Stop"
+
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.stopped = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.mu.Unlock()
+
+	p.log.Info("draining event queues...", slog.String("op", op), slog.Int("queued", p.depth()))
+	p.wg.Wait()
+	p.log.Info("event queues are drained", slog.String("op", op))
+}
+
+func (p *Pool) work(queue chan func()) {
+	defer p.wg.Done()
+
+	for task := range queue {
+		task()
+		p.processed.Add(1)
+	}
+}
+
+// index returns number of the worker which handles the key.
+func (p *Pool) index(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// depth returns number of tasks waiting in all queues.
+func (p *Pool) depth() int {
+	depth := 0
+	for _, queue := range p.queues {
+		depth += len(queue)
+	}
+
+	return depth
+}
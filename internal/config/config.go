@@ -4,15 +4,35 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 )
 
+const (
+	RepoDriverTarantool = "tarantool"
+	RepoDriverMemory    = "memory"
+)
+
 type Config struct {
 	Env        string `env:"ENV" env-required:"true"`
+	Repo       Repo
 	Tarantool  Tarantool
 	Mattermost Mattermost
+	HTTPServer HTTPServer
+	Requests   Requests
+	Workers    Workers
+	Commands   Commands
+	Leader     Leader
+	API        API
+	Anonymity  Anonymity
+}
+
+// Repo selects storage for polls and votes.
+// "memory" keeps everything in process and doesn't need tarantool.
+type Repo struct {
+	Driver string `env:"REPO_DRIVER" env-default:"tarantool"`
 }
 
 type Tarantool struct {
@@ -26,6 +46,88 @@ type Mattermost struct {
 	Token  string `env:"MM_TOKEN" env-required:"true"`
 	Team   string `env:"MM_TEAM" env-required:"true"`
 	Server *url.URL
+	// ListenWebSocket can be turned off in case websocket is blocked,
+	// then commands are received only via slash commands and outgoing webhooks.
+	ListenWebSocket bool `env:"MM_LISTEN_WEBSOCKET" env-default:"true"`
+	// CommandTokens are tokens of slash commands and outgoing webhooks
+	// which are allowed to call the bot. Mattermost generates one per command.
+	CommandTokens []string `env:"MM_COMMAND_TOKENS" env-separator:","`
+	// ActionsURL is the bot's HTTP server address reachable by Mattermost.
+	// Poll posts get vote buttons only when it and ActionsToken are set.
+	ActionsURL string `env:"MM_ACTIONS_URL"`
+	// ActionsToken is passed in button context and checked on every click,
+	// so that nobody else could vote on behalf of other users.
+	ActionsToken string `env:"MM_ACTIONS_TOKEN"`
+	// CAFile is a PEM file with certificates trusted in addition to the system ones,
+	// e.g. for Mattermost with self-signed certificate.
+	CAFile string `env:"MM_CA_FILE"`
+	// InsecureSkipVerify turns off verification of Mattermost certificate. Use only for development.
+	InsecureSkipVerify bool `env:"MM_TLS_INSECURE_SKIP_VERIFY" env-default:"false"`
+	// ReconnectMinDelay and ReconnectMaxDelay limit delays between websocket reconnects.
+	ReconnectMinDelay time.Duration `env:"MM_RECONNECT_MIN_DELAY" env-default:"1s"`
+	ReconnectMaxDelay time.Duration `env:"MM_RECONNECT_MAX_DELAY" env-default:"1m"`
+	// PingInterval is how often websocket is pinged. Connection is considered dead
+	// and reopened in case there is no pong within PongTimeout after the ping.
+	PingInterval time.Duration `env:"MM_PING_INTERVAL" env-default:"30s"`
+	PongTimeout  time.Duration `env:"MM_PONG_TIMEOUT" env-default:"10s"`
+	// UsersCacheTTL is how long names of users are cached.
+	// User is fetched again earlier in case the user is updated while websocket is connected.
+	UsersCacheTTL time.Duration `env:"MM_USERS_CACHE_TTL" env-default:"10m"`
+}
+
+// Anonymity configures anonymous polls.
+type Anonymity struct {
+	// Secret is used to hash voters of anonymous polls and must not change,
+	// otherwise users could vote again. Anonymous polls are disabled in case it's empty.
+	Secret string `env:"ANON_SECRET"`
+}
+
+// Requests configures handling of commands, button clicks and API requests.
+type Requests struct {
+	// Timeout limits handling of one request including all calls to the repo.
+	Timeout time.Duration `env:"REQUEST_TIMEOUT" env-default:"5s"`
+}
+
+// Workers configures concurrent handling of websocket events.
+type Workers struct {
+	// Count is a number of events handled at the same time.
+	// Events of one channel are always handled one by one.
+	Count int `env:"WORKERS_COUNT" env-default:"8"`
+	// QueueSize limits events waiting for each worker,
+	// reading of websocket is paused while the queue is full.
+	QueueSize int `env:"WORKERS_QUEUE_SIZE" env-default:"64"`
+}
+
+// Commands configures storage of processed commands used to skip posts delivered twice.
+type Commands struct {
+	// TTL is how long processed commands are kept.
+	TTL time.Duration `env:"COMMANDS_TTL" env-default:"24h"`
+	// CleanupInterval is how often expired commands are deleted.
+	CleanupInterval time.Duration `env:"COMMANDS_CLEANUP_INTERVAL" env-default:"1h"`
+}
+
+// Leader configures election of the replica which listens to websocket,
+// finishes polls by deadline and cleans up processed commands.
+type Leader struct {
+	// ID identifies the replica, it's generated from hostname in case it's empty.
+	ID string `env:"REPLICA_ID"`
+	// LeaseTTL is how long leadership is kept without renewal,
+	// standbys take over within it after the leader dies.
+	LeaseTTL      time.Duration `env:"LEADER_LEASE_TTL" env-default:"10s"`
+	RenewInterval time.Duration `env:"LEADER_RENEW_INTERVAL" env-default:"3s"`
+}
+
+// API configures REST API served by the bot's HTTP server.
+type API struct {
+	// Tokens are "user_id:token" pairs. Requests with the token are made
+	// on behalf of the user. API is disabled in case there are no tokens.
+	Tokens []string `env:"API_TOKENS" env-separator:","`
+}
+
+type HTTPServer struct {
+	Address     string        `env:"HTTP_ADDRESS" env-default:":3302"`
+	Timeout     time.Duration `env:"HTTP_TIMEOUT" env-default:"5s"`
+	IdleTimeout time.Duration `env:"HTTP_IDLE_TIMEOUT" env-default:"60s"`
 }
 
 func MustLoad() *Config {
@@ -40,9 +142,15 @@ func MustLoad() *Config {
 		log.Fatalf("%s: failed to read config from env vars: %v", op, err)
 	}
 
+	switch cfg.Repo.Driver {
+	case RepoDriverTarantool, RepoDriverMemory:
+	default:
+		log.Fatalf("%s: unknown repo driver %q", op, cfg.Repo.Driver)
+	}
+
 	server, err := url.Parse(os.Getenv("MM_SERVER"))
 	if err != nil {
-		log.Fatalf("%s: failed to parse mattermost server url: %w", op, err)
+		log.Fatalf("%s: failed to parse mattermost server url: %v", op, err)
 	}
 
 	if server.String() == "" {
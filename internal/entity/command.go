@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+type CommandStatus string
+
+const (
+	// CommandStatusProcessing means command is being executed,
+	// or the bot was stopped while executing it.
+	CommandStatusProcessing CommandStatus = "processing"
+	// CommandStatusDone means command is executed, but its response isn't sent yet.
+	CommandStatusDone CommandStatus = "done"
+	// CommandStatusSent means response to the command is sent.
+	CommandStatusSent CommandStatus = "sent"
+)
+
+// ProcessedCommand is an outcome of the command posted to Mattermost.
+type ProcessedCommand struct {
+	PostID      string
+	Channel     string
+	ProcessedAt time.Time
+	Status      CommandStatus
+	// Response is a text of the bot response, it's empty for commands without response.
+	Response string
+	// Public responses are posted to the channel, the other ones are replies to the post.
+	Public bool
+}
@@ -0,0 +1,39 @@
+package entity
+
+import "slices"
+
+// Cursor is a high-water mark of posts handled by the bot in a channel.
+// Posts after it are replayed when websocket reconnects.
+type Cursor struct {
+	Channel string
+	// LastPostAt is creation time of the last handled post in unix milliseconds, as in Mattermost.
+	LastPostAt int64
+	// PostIDs are IDs of handled posts created at LastPostAt,
+	// since several posts can be created within one millisecond.
+	// Empty PostIDs mean that all posts created at LastPostAt are handled.
+	PostIDs []string
+}
+
+// IsHandled reports whether post created at createAt is behind the cursor.
+func (c *Cursor) IsHandled(postID string, createAt int64) bool {
+	if createAt != c.LastPostAt {
+		return createAt < c.LastPostAt
+	}
+
+	return len(c.PostIDs) == 0 || slices.Contains(c.PostIDs, postID)
+}
+
+// Advance moves the cursor to the post. It reports false in case post is behind the cursor.
+func (c *Cursor) Advance(postID string, createAt int64) bool {
+	if c.IsHandled(postID, createAt) {
+		return false
+	}
+
+	if createAt > c.LastPostAt {
+		c.LastPostAt = createAt
+		c.PostIDs = nil
+	}
+	c.PostIDs = append(c.PostIDs, postID)
+
+	return true
+}
@@ -0,0 +1,49 @@
+package entity
+
+import "time"
+
+// EditAction is a kind of change of the poll.
+type EditAction string
+
+const (
+	EditRenamePoll   EditAction = "rename_poll"
+	EditAddOption    EditAction = "add_option"
+	EditRenameOption EditAction = "rename_option"
+	EditRemoveOption EditAction = "remove_option"
+	EditMoveOption   EditAction = "move_option"
+	EditReopenPoll   EditAction = "reopen_poll"
+)
+
+// PollEdit is a change of the poll made by its creator.
+// Edits are kept as history of the poll.
+type PollEdit struct {
+	ID       uint64
+	PollID   uint64
+	Editor   string
+	EditedAt time.Time
+	Action   EditAction
+	// OptionNum is a number of the edited option before the edit.
+	// It's zero for edits of the poll itself.
+	OptionNum uint64
+	// OldValue and NewValue are values before and after the edit,
+	// e.g. names of the renamed option or positions of the moved one.
+	OldValue string
+	NewValue string
+}
+
+// Reopening is a finished poll reopened by its creator.
+type Reopening struct {
+	Poll Poll
+	// SupersededPostID is ID of the post with results posted when poll was finished.
+	// Results there aren't final anymore. It's empty in case there's no such post.
+	SupersededPostID string
+}
+
+// OptionRemoval is an option removed from the poll with votes which had to be changed.
+type OptionRemoval struct {
+	Option Option
+	// Votes are changed votes of users without the removed option.
+	// Votes with empty OptionIDs were deleted, since nothing was left in them.
+	// Ballots of anonymous polls aren't returned, since their voters aren't known.
+	Votes []Vote
+}
@@ -1,10 +1,97 @@
 package entity
 
+import "time"
+
+// PollType defines how people vote in the poll and how results are counted.
+type PollType string
+
+const (
+	// PollTypeSingle allows to choose only one option.
+	PollTypeSingle PollType = "single"
+	// PollTypeMulti allows to choose several options.
+	PollTypeMulti PollType = "multi"
+	// PollTypeRanked allows to rank options in order of preference.
+	// Results are counted with instant-runoff voting.
+	PollTypeRanked PollType = "ranked"
+	// PollTypeScore allows to rate each option from 0 to MaxScore.
+	// Options are compared by average score.
+	PollTypeScore PollType = "score"
+	// PollTypeApproval allows to approve up to Poll.MaxChoices options.
+	PollTypeApproval PollType = "approval"
+)
+
+// MaxScore is the highest score option can get in score polls.
+const MaxScore = 5
+
+// AllowsManyOptions reports whether vote may contain more than one option.
+func (t PollType) AllowsManyOptions() bool {
+	return t != PollTypeSingle
+}
+
 type Poll struct {
-	ID          uint64
-	Name        string
-	Creator     string
-	Channel     string
-	IsFinished  bool
-	IsMultiVote bool
+	ID         uint64
+	Name       string
+	Creator    string
+	Channel    string
+	IsFinished bool
+	Type       PollType
+	// PostID is ID of the Mattermost post which announces the poll
+	// and is kept up to date with its state.
+	PostID string
+	// ClosesAt is the time when poll is finished automatically.
+	// Zero value means that poll doesn't have a deadline.
+	ClosesAt time.Time
+	// IsAnonymous polls don't store voters next to their choices.
+	IsAnonymous bool
+	// Salt is a random per-poll value which voters of anonymous polls are hashed with.
+	Salt string
+	// MaxChoices limits number of options in one vote of approval poll.
+	// Zero means there is no limit.
+	MaxChoices uint64
+	// ShowVoters polls list voters of each option in their results.
+	// Anonymous polls can't show voters.
+	ShowVoters bool
+	// ResultsPostID is ID of the post with results posted when poll was finished by deadline.
+	ResultsPostID string
+	// ReopenedBy and ReopenedAt tell who reopened the finished poll last time and when.
+	// ReopenedBy is empty in case poll was never reopened.
+	ReopenedBy string
+	ReopenedAt time.Time
+}
+
+// PollInfo is a definition of the poll with number of people who voted in it.
+type PollInfo struct {
+	Poll    Poll
+	Options []Option
+	Voters  uint64
+}
+
+// PollStatus selects polls by whether they are finished.
+type PollStatus string
+
+const (
+	PollStatusAll      PollStatus = "all"
+	PollStatusOpen     PollStatus = "open"
+	PollStatusFinished PollStatus = "finished"
+)
+
+// PollFilter selects polls either of the channel or of the creator.
+type PollFilter struct {
+	Channel string
+	Creator string
+	// Status is applied only to polls of the channel.
+	Status PollStatus
+}
+
+// PollSummary is a poll in the list of polls.
+type PollSummary struct {
+	Poll   Poll
+	Voters uint64
+}
+
+// PollPage is a page of polls sorted from the newest to the oldest.
+type PollPage struct {
+	Polls []PollSummary
+	// NextBefore is ID to request the next page with, it's 0 on the last page.
+	NextBefore uint64
 }
@@ -0,0 +1,78 @@
+package entity
+
+import "slices"
+
+// Results of the poll.
+type Results struct {
+	Poll Poll
+	// Options are sorted by option number.
+	Options []OptionResult
+	// Rounds of instant-runoff counting, only for ranked polls.
+	Rounds []Round
+	// Winners are numbers of options which won.
+	// There are several of them in case of a tie.
+	Winners []uint64
+	// TotalVoters is a number of users who voted in the poll.
+	TotalVoters uint64
+	// TotalSelections is a number of options chosen in all votes.
+	// It's greater than TotalVoters in case votes contain several options.
+	TotalSelections uint64
+}
+
+// IsTie reports whether several options won.
+func (r *Results) IsTie() bool {
+	return len(r.Winners) > 1
+}
+
+// HasVoters reports whether options are given with their voters.
+func (r *Results) HasVoters() bool {
+	for _, opt := range r.Options {
+		if len(opt.Voters) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsWinner reports whether option with the number won.
+func (r *Results) IsWinner(num uint64) bool {
+	return slices.Contains(r.Winners, num)
+}
+
+// PollExport is results of the poll together with votes of each voter.
+type PollExport struct {
+	Results Results
+	// Votes are empty for anonymous polls, since their ballots don't have voters.
+	Votes []Vote
+}
+
+type OptionResult struct {
+	Option Option
+	// Votes is a number of votes for the option.
+	// In ranked polls it is a number of first preferences,
+	// in score polls it is a number of voters who rated the option.
+	Votes uint64
+	// Percent is a share of voters who voted for the option, from 0 to 100.
+	// Shares of options may sum up to more than 100 in case votes contain several options.
+	Percent float64
+	// Average score of the option, only for score polls.
+	Average float64
+	// Distribution[s] is a number of voters who gave score s to the option,
+	// only for score polls.
+	Distribution []uint64
+	// Voters are IDs of users who chose (ranked, rated) the option in order they voted.
+	// They are filled only when voters are requested and never for anonymous polls.
+	Voters []string
+}
+
+// Round of instant-runoff counting.
+type Round struct {
+	// Votes[i] is a number of ballots counted for option with number i+1.
+	// Options eliminated in previous rounds have zero.
+	Votes []uint64
+	// Eliminated are numbers of options eliminated after the round.
+	Eliminated []uint64
+	// Exhausted is a number of ballots with all ranked options eliminated.
+	Exhausted uint64
+}
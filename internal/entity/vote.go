@@ -5,4 +5,17 @@ type Vote struct {
 	PollID    uint64
 	OptionIDs []uint64
 	User      string
+	// Scores[i] is a score given to OptionIDs[i] in score polls.
+	// It's empty in polls of other types.
+	Scores []uint64
+}
+
+// UserVote is a current choice of the user with chosen options resolved by their numbers.
+type UserVote struct {
+	Poll Poll
+	// Options are chosen options in order they were given in the vote,
+	// i.e. in order of preference in ranked polls.
+	Options []Option
+	// Scores[i] is a score given to Options[i] in score polls.
+	Scores []uint64
 }
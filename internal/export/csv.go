@@ -0,0 +1,64 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"vote-bot/internal/entity"
+)
+
+// writeCSV writes table of options with their results.
+// Table of ballots follows it after an empty line, one row per chosen option.
+func writeCSV(w io.Writer, export *Export) error {
+	results := export.Results
+	isScore := results.Poll.Type == entity.PollTypeScore
+
+	cw := csv.NewWriter(w)
+
+	header := []string{"option_num", "option", "votes", "percent"}
+	if isScore {
+		header = append(header, "average")
+	}
+	header = append(header, "winner")
+	cw.Write(header)
+
+	for _, opt := range results.Options {
+		row := []string{
+			strconv.FormatUint(opt.Option.Num, 10),
+			opt.Option.Name,
+			strconv.FormatUint(opt.Votes, 10),
+			strconv.FormatFloat(opt.Percent, 'f', 2, 64),
+		}
+		if isScore {
+			row = append(row, strconv.FormatFloat(opt.Average, 'f', 2, 64))
+		}
+		row = append(row, strconv.FormatBool(results.IsWinner(opt.Option.Num)))
+		cw.Write(row)
+	}
+
+	if len(export.Ballots) != 0 {
+		cw.Write(nil)
+
+		// position is a rank of the option in ranked polls.
+		cw.Write([]string{"voter", "position", "option_num", "option", "score"})
+		for _, ballot := range export.Ballots {
+			for i, opt := range ballot.Options {
+				score := ""
+				if i < len(ballot.Scores) {
+					score = strconv.FormatUint(ballot.Scores[i], 10)
+				}
+				cw.Write([]string{
+					ballot.Voter,
+					strconv.Itoa(i + 1),
+					strconv.FormatUint(opt.Num, 10),
+					opt.Name,
+					score,
+				})
+			}
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
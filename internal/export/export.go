@@ -0,0 +1,113 @@
+// Package export writes results of polls to files: CSV, JSON and Markdown.
+// It doesn't depend on Mattermost, so that any frontend of the bot could use it.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"vote-bot/internal/entity"
+)
+
+// Format of the exported file.
+type Format string
+
+const (
+	FormatCSV      Format = "csv"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "md"
+)
+
+// Formats are all supported formats, the first one is the default.
+var Formats = []Format{FormatCSV, FormatJSON, FormatMarkdown}
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Export is the poll prepared for writing to a file.
+type Export struct {
+	Results *entity.Results
+	// Ballots are votes of each voter in order they were cast.
+	// They are empty for anonymous polls.
+	Ballots []Ballot
+}
+
+// Ballot is a vote with the voter and options resolved to names.
+type Ballot struct {
+	// Voter is a name of the user who voted, e.g. username.
+	Voter string
+	// Options are chosen options in order they were given in the vote,
+	// i.e. in order of preference in ranked polls.
+	Options []entity.Option
+	// Scores[i] is a score given to Options[i] in score polls.
+	Scores []uint64
+}
+
+// New prepares the poll for export. Voters are named by names[vote.User],
+// user ID is used in case the name isn't known.
+func New(poll *entity.PollExport, names map[string]string) *Export {
+	export := &Export{
+		Results: &poll.Results,
+		Ballots: make([]Ballot, 0, len(poll.Votes)),
+	}
+
+	for _, vote := range poll.Votes {
+		voter, ok := names[vote.User]
+		if !ok {
+			voter = vote.User
+		}
+
+		ballot := Ballot{Voter: voter, Scores: vote.Scores}
+		for _, num := range vote.OptionIDs {
+			ballot.Options = append(ballot.Options, optionByNum(&poll.Results, num))
+		}
+		export.Ballots = append(export.Ballots, ballot)
+	}
+
+	return export
+}
+
+// optionByNum returns option of the poll by its number.
+// Options of results are sorted by number starting from 1.
+func optionByNum(results *entity.Results, num uint64) entity.Option {
+	if num == 0 || num > uint64(len(results.Options)) {
+		return entity.Option{Num: num}
+	}
+
+	return results.Options[num-1].Option
+}
+
+// Write writes the export to w in the format.
+func Write(w io.Writer, format Format, export *Export) error {
+	const op = "export.Write"
+
+	var err error
+	switch format {
+	case FormatCSV:
+		err = writeCSV(w, export)
+	case FormatJSON:
+		err = writeJSON(w, export)
+	case FormatMarkdown:
+		err = writeMarkdown(w, export)
+	default:
+		return fmt.Errorf("%s: %w: %s", op, ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// FileName returns name of the file the poll is exported to, e.g. "poll-5.csv".
+func FileName(poll *entity.Poll, format Format) string {
+	return fmt.Sprintf("poll-%d.%s", poll.ID, format)
+}
+
+// status returns status of the poll written to files.
+func status(poll *entity.Poll) string {
+	if poll.IsFinished {
+		return "finished"
+	}
+
+	return "open"
+}
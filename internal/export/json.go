@@ -0,0 +1,95 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type pollJSON struct {
+	ID          uint64       `json:"id"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Status      string       `json:"status"`
+	IsAnonymous bool         `json:"is_anonymous"`
+	ClosesAt    *time.Time   `json:"closes_at,omitempty"`
+	TotalVoters uint64       `json:"total_voters"`
+	Winners     []uint64     `json:"winners"`
+	Options     []optionJSON `json:"options"`
+	Ballots     []ballotJSON `json:"ballots,omitempty"`
+}
+
+type optionJSON struct {
+	Num     uint64   `json:"num"`
+	Name    string   `json:"name"`
+	Votes   uint64   `json:"votes"`
+	Percent float64  `json:"percent"`
+	Average *float64 `json:"average,omitempty"`
+}
+
+type ballotJSON struct {
+	Voter   string       `json:"voter"`
+	Choices []choiceJSON `json:"choices"`
+}
+
+type choiceJSON struct {
+	Num   uint64  `json:"num"`
+	Name  string  `json:"name"`
+	Score *uint64 `json:"score,omitempty"`
+}
+
+// writeJSON writes the poll with its options and ballots as one JSON object.
+func writeJSON(w io.Writer, export *Export) error {
+	results := export.Results
+	poll := &results.Poll
+
+	out := pollJSON{
+		ID:          poll.ID,
+		Name:        poll.Name,
+		Type:        string(poll.Type),
+		Status:      status(poll),
+		IsAnonymous: poll.IsAnonymous,
+		TotalVoters: results.TotalVoters,
+		Winners:     results.Winners,
+		Options:     make([]optionJSON, 0, len(results.Options)),
+	}
+	if !poll.ClosesAt.IsZero() {
+		closesAt := poll.ClosesAt.UTC()
+		out.ClosesAt = &closesAt
+	}
+	if out.Winners == nil {
+		out.Winners = []uint64{}
+	}
+
+	for _, opt := range results.Options {
+		option := optionJSON{
+			Num:     opt.Option.Num,
+			Name:    opt.Option.Name,
+			Votes:   opt.Votes,
+			Percent: opt.Percent,
+		}
+		if opt.Distribution != nil {
+			average := opt.Average
+			option.Average = &average
+		}
+		out.Options = append(out.Options, option)
+	}
+
+	for _, ballot := range export.Ballots {
+		b := ballotJSON{Voter: ballot.Voter, Choices: make([]choiceJSON, 0, len(ballot.Options))}
+		for i, opt := range ballot.Options {
+			choice := choiceJSON{Num: opt.Num, Name: opt.Name}
+			if i < len(ballot.Scores) {
+				score := ballot.Scores[i]
+				choice.Score = &score
+			}
+			b.Choices = append(b.Choices, choice)
+		}
+		out.Ballots = append(out.Ballots, b)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(out)
+}
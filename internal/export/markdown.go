@@ -0,0 +1,74 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"vote-bot/internal/entity"
+)
+
+// writeMarkdown writes the poll as a document with tables of results and ballots.
+func writeMarkdown(w io.Writer, export *Export) error {
+	results := export.Results
+	poll := &results.Poll
+	isScore := poll.Type == entity.PollTypeScore
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Poll %d: %s\n\n", poll.ID, poll.Name)
+	fmt.Fprintf(&b, "- Type: %s\n", poll.Type)
+	fmt.Fprintf(&b, "- Status: %s\n", status(poll))
+	if poll.IsAnonymous {
+		b.WriteString("- Anonymous: yes\n")
+	}
+	if !poll.ClosesAt.IsZero() {
+		fmt.Fprintf(&b, "- Deadline: %s\n", poll.ClosesAt.UTC().Format("2006-01-02 15:04 MST"))
+	}
+	fmt.Fprintf(&b, "- Voters: %d\n", results.TotalVoters)
+
+	b.WriteString("\n## Results\n\n")
+	if isScore {
+		b.WriteString("| # | Option | Votes | Percent | Average | Winner |\n")
+		b.WriteString("|--:|:--|--:|--:|--:|:-:|\n")
+	} else {
+		b.WriteString("| # | Option | Votes | Percent | Winner |\n")
+		b.WriteString("|--:|:--|--:|--:|:-:|\n")
+	}
+	for _, opt := range results.Options {
+		winner := ""
+		if results.IsWinner(opt.Option.Num) {
+			winner = "yes"
+		}
+		fmt.Fprintf(&b, "| %d | %s | %d | %.2f%% | ", opt.Option.Num, escapeCell(opt.Option.Name), opt.Votes, opt.Percent)
+		if isScore {
+			fmt.Fprintf(&b, "%.2f | ", opt.Average)
+		}
+		fmt.Fprintf(&b, "%s |\n", winner)
+	}
+
+	if len(export.Ballots) != 0 {
+		b.WriteString("\n## Ballots\n\n")
+		b.WriteString("| Voter | Choice |\n")
+		b.WriteString("|:--|:--|\n")
+		for _, ballot := range export.Ballots {
+			choices := make([]string, 0, len(ballot.Options))
+			for i, opt := range ballot.Options {
+				choice := fmt.Sprintf("%d) %s", opt.Num, opt.Name)
+				if i < len(ballot.Scores) {
+					choice += fmt.Sprintf(": %d", ballot.Scores[i])
+				}
+				choices = append(choices, escapeCell(choice))
+			}
+			fmt.Fprintf(&b, "| %s | %s |\n", escapeCell(ballot.Voter), strings.Join(choices, "; "))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// escapeCell escapes text put in cell of markdown table.
+func escapeCell(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
@@ -5,4 +5,8 @@ import "errors"
 var (
 	ErrPollDoesNotExist = errors.New("poll with this id does not exist")
 	ErrNoOptionsFound   = errors.New("no options for the poll was found")
+	ErrVoteDoesNotExist = errors.New("user hasn't voted in this poll")
+	ErrVoterExists      = errors.New("user has already voted in this anonymous poll")
+
+	ErrCursorDoesNotExist = errors.New("no cursor for the channel")
 )
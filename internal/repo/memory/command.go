@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"vote-bot/internal/entity"
+)
+
+// ClaimCommand saves command with processing status in case its post wasn't handled yet.
+// Otherwise, it returns the command saved before.
+func (r *Repo) ClaimCommand(ctx context.Context, cmd entity.ProcessedCommand) (*entity.ProcessedCommand, error) {
+	const op = "repo.memory.ClaimCommand"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.commands[cmd.PostID]; ok {
+		return &existing, nil
+	}
+
+	// Tarantool keeps time in unix seconds.
+	r.commands[cmd.PostID] = entity.ProcessedCommand{
+		PostID:      cmd.PostID,
+		Channel:     cmd.Channel,
+		ProcessedAt: cmd.ProcessedAt.Truncate(time.Second),
+		Status:      entity.CommandStatusProcessing,
+	}
+
+	return nil, nil
+}
+
+// SaveCommand replaces the command with its outcome.
+func (r *Repo) SaveCommand(ctx context.Context, cmd entity.ProcessedCommand) error {
+	const op = "repo.memory.SaveCommand"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cmd.ProcessedAt = cmd.ProcessedAt.Truncate(time.Second)
+	r.commands[cmd.PostID] = cmd
+
+	return nil
+}
+
+// DeleteCommandsBefore deletes commands processed before the time and returns their number.
+func (r *Repo) DeleteCommandsBefore(ctx context.Context, before time.Time) (uint64, error) {
+	const op = "repo.memory.DeleteCommandsBefore"
+
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted uint64
+	for postID, cmd := range r.commands {
+		if cmd.ProcessedAt.Before(before) {
+			delete(r.commands, postID)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
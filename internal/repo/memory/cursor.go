@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+)
+
+// GetCursor returns high-water mark of handled posts in the channel.
+func (r *Repo) GetCursor(ctx context.Context, channel string) (*entity.Cursor, error) {
+	const op = "repo.memory.GetCursor"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cursor, ok := r.cursors[channel]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, repo.ErrCursorDoesNotExist)
+	}
+	cursor.PostIDs = slices.Clone(cursor.PostIDs)
+
+	return &cursor, nil
+}
+
+// SaveCursor creates or replaces the cursor of the channel.
+func (r *Repo) SaveCursor(ctx context.Context, cursor entity.Cursor) error {
+	const op = "repo.memory.SaveCursor"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cursor.PostIDs = slices.Clone(cursor.PostIDs)
+	r.cursors[cursor.Channel] = cursor
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	"vote-bot/internal/entity"
+)
+
+// RenamePoll changes name of the poll to edit.NewValue and saves the edit.
+func (r *Repo) RenamePoll(ctx context.Context, edit entity.PollEdit) error {
+	const op = "repo.memory.RenamePoll"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if poll, ok := r.polls[edit.PollID]; ok {
+		poll.Name = edit.NewValue
+		r.polls[edit.PollID] = poll
+	}
+	r.saveEdit(edit)
+
+	return nil
+}
+
+// ReopenPoll makes the finished poll open with deadline closesAt and saves the edit.
+func (r *Repo) ReopenPoll(ctx context.Context, edit entity.PollEdit, closesAt time.Time) error {
+	const op = "repo.memory.ReopenPoll"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if poll, ok := r.polls[edit.PollID]; ok {
+		poll.IsFinished = false
+		// Tarantool keeps times in unix seconds.
+		poll.ClosesAt = closesAt.Truncate(time.Second)
+		poll.ResultsPostID = ""
+		poll.ReopenedBy = edit.Editor
+		poll.ReopenedAt = edit.EditedAt.Truncate(time.Second)
+		r.polls[edit.PollID] = poll
+	}
+	r.saveEdit(edit)
+
+	return nil
+}
+
+// AddOption adds option named edit.NewValue to the poll and saves the edit.
+// Option gets the next number like in add_option() in tarantool.
+func (r *Repo) AddOption(ctx context.Context, edit entity.PollEdit) (*entity.Option, error) {
+	const op = "repo.memory.AddOption"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.optionSeq++
+	option := entity.Option{
+		ID:     r.optionSeq,
+		PollID: edit.PollID,
+		Name:   edit.NewValue,
+		Num:    uint64(len(r.options[edit.PollID]) + 1),
+	}
+	r.options[edit.PollID] = append(r.options[edit.PollID], option)
+
+	edit.OptionNum = option.Num
+	r.saveEdit(edit)
+
+	return &option, nil
+}
+
+// RenameOption changes name of option edit.OptionNum to edit.NewValue and saves the edit.
+func (r *Repo) RenameOption(ctx context.Context, edit entity.PollEdit) error {
+	const op = "repo.memory.RenameOption"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	options := r.options[edit.PollID]
+	i := slices.IndexFunc(options, func(option entity.Option) bool {
+		return option.Num == edit.OptionNum
+	})
+	if i == -1 {
+		return fmt.Errorf("%s: option %d not found", op, edit.OptionNum)
+	}
+	options[i].Name = edit.NewValue
+	r.saveEdit(edit)
+
+	return nil
+}
+
+// RenumberOptions gives option with number i+1 number nums[i], options with zero number are removed.
+// Options in votes and ballots are renumbered too and the edit is saved,
+// the same way renumber_options() does it in tarantool.
+// It returns votes which lost options, ones left without options are deleted.
+func (r *Repo) RenumberOptions(ctx context.Context, edit entity.PollEdit, nums []uint64) ([]entity.Vote, error) {
+	const op = "repo.memory.RenumberOptions"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newNum := func(num uint64) uint64 {
+		if num == 0 || num > uint64(len(nums)) {
+			return 0
+		}
+		return nums[num-1]
+	}
+
+	options := make([]entity.Option, 0, len(r.options[edit.PollID]))
+	for _, option := range r.options[edit.PollID] {
+		if option.Num = newNum(option.Num); option.Num != 0 {
+			options = append(options, option)
+		}
+	}
+	// Options are kept sorted by number like GetOptions returns them.
+	slices.SortFunc(options, func(a, b entity.Option) int {
+		return cmp.Compare(a.Num, b.Num)
+	})
+	r.options[edit.PollID] = options
+
+	var changed []entity.Vote
+	votes := make([]entity.Vote, 0, len(r.votes[edit.PollID]))
+	for _, vote := range r.votes[edit.PollID] {
+		newVote := renumberChoice(vote, newNum)
+		if len(newVote.OptionIDs) != 0 {
+			votes = append(votes, newVote)
+		}
+		if len(newVote.OptionIDs) < len(vote.OptionIDs) {
+			changed = append(changed, cloneVote(newVote))
+		}
+	}
+	r.votes[edit.PollID] = votes
+
+	// Voters of empty ballots are kept, since they can't be found by ballot,
+	// so voters of anonymous polls are counted by ballots.
+	for ballotID, ballot := range r.ballots {
+		if ballot.PollID != edit.PollID {
+			continue
+		}
+		newBallot := renumberChoice(ballot, newNum)
+		if len(newBallot.OptionIDs) == 0 {
+			delete(r.ballots, ballotID)
+			continue
+		}
+		r.ballots[ballotID] = newBallot
+	}
+
+	r.saveEdit(edit)
+
+	return changed, nil
+}
+
+// renumberChoice returns copy of the vote with options renumbered by newNum,
+// options with zero number are dropped together with their scores.
+func renumberChoice(vote entity.Vote, newNum func(uint64) uint64) entity.Vote {
+	newVote := vote
+	newVote.OptionIDs = make([]uint64, 0, len(vote.OptionIDs))
+	newVote.Scores = nil
+	if vote.Scores != nil {
+		newVote.Scores = make([]uint64, 0, len(vote.Scores))
+	}
+
+	for i, num := range vote.OptionIDs {
+		num = newNum(num)
+		if num == 0 {
+			continue
+		}
+		newVote.OptionIDs = append(newVote.OptionIDs, num)
+		if i < len(vote.Scores) {
+			newVote.Scores = append(newVote.Scores, vote.Scores[i])
+		}
+	}
+
+	return newVote
+}
+
+// GetPollEdits returns edits of the poll in order they were made.
+func (r *Repo) GetPollEdits(ctx context.Context, pollID uint64) ([]entity.PollEdit, error) {
+	const op = "repo.memory.GetPollEdits"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.edits[pollID]), nil
+}
+
+// saveEdit appends the edit to history of the poll, r.mu must be held.
+// Tarantool keeps time of the edit in unix seconds.
+func (r *Repo) saveEdit(edit entity.PollEdit) {
+	r.editSeq++
+	edit.ID = r.editSeq
+	edit.EditedAt = time.Unix(edit.EditedAt.Unix(), 0)
+	r.edits[edit.PollID] = append(r.edits[edit.PollID], edit)
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// lease is held by the holder until it expires.
+type lease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// AcquireLease takes the lease for ttl in case it's free, expired or already held by the holder.
+func (r *Repo) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	const op = "repo.memory.AcquireLease"
+
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if l, ok := r.leases[name]; ok && l.holder != holder && l.expiresAt.After(now) {
+		return false, nil
+	}
+	r.leases[name] = lease{holder: holder, expiresAt: now.Add(ttl)}
+
+	return true, nil
+}
+
+// ReleaseLease frees the lease in case it's held by the holder.
+func (r *Repo) ReleaseLease(ctx context.Context, name string, holder string) error {
+	const op = "repo.memory.ReleaseLease"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l, ok := r.leases[name]; ok && l.holder == holder {
+		delete(r.leases, name)
+	}
+
+	return nil
+}
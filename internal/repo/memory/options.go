@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+)
+
+// GetOptions returns all options that belong to poll with pollID.
+func (r *Repo) GetOptions(ctx context.Context, pollID uint64) ([]entity.Option, error) {
+	const op = "repo.memory.GetOptions"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	options := r.options[pollID]
+	if len(options) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, repo.ErrNoOptionsFound)
+	}
+
+	return cloneOptions(options), nil
+}
+
+func cloneOptions(options []entity.Option) []entity.Option {
+	return append([]entity.Option(nil), options...)
+}
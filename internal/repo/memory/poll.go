@@ -0,0 +1,247 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+)
+
+// CreatePollWithOptions saves new poll and its options.
+// Options are numbered in the order they were passed, starting from 1.
+func (r *Repo) CreatePollWithOptions(ctx context.Context, poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error) {
+	const op = "repo.memory.CreatePollWithOptions"
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pollSeq++
+	poll.ID = r.pollSeq
+	poll.IsFinished = false
+	// Tarantool keeps deadline in unix seconds.
+	poll.ClosesAt = poll.ClosesAt.Truncate(time.Second)
+	r.polls[poll.ID] = poll
+
+	newOptions := make([]entity.Option, 0, len(options))
+	for i, option := range options {
+		r.optionSeq++
+		newOptions = append(newOptions, entity.Option{
+			ID:     r.optionSeq,
+			PollID: poll.ID,
+			Name:   option.Name,
+			Num:    uint64(i + 1),
+		})
+	}
+	r.options[poll.ID] = newOptions
+
+	return &poll, cloneOptions(newOptions), nil
+}
+
+// GetPoll returns info about poll by its ID.
+func (r *Repo) GetPoll(ctx context.Context, pollID uint64) (*entity.Poll, error) {
+	const op = "repo.memory.GetPoll"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	poll, ok := r.polls[pollID]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, repo.ErrPollDoesNotExist)
+	}
+
+	return &poll, nil
+}
+
+// FinishPoll finishes the poll by setting IsFinished to true.
+//
+// Like update request in tarantool, it does nothing if poll doesn't exist.
+func (r *Repo) FinishPoll(ctx context.Context, pollID uint64) error {
+	const op = "repo.memory.FinishPoll"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	poll, ok := r.polls[pollID]
+	if !ok {
+		return nil
+	}
+
+	poll.IsFinished = true
+	r.polls[pollID] = poll
+
+	return nil
+}
+
+// GetPendingPolls returns polls which have a deadline and are not finished yet.
+// Polls are sorted by deadline like in poll_closes_at index in tarantool.
+func (r *Repo) GetPendingPolls(ctx context.Context) ([]entity.Poll, error) {
+	const op = "repo.memory.GetPendingPolls"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var polls []entity.Poll
+	for _, poll := range r.polls {
+		if !poll.ClosesAt.IsZero() && !poll.IsFinished {
+			polls = append(polls, poll)
+		}
+	}
+
+	slices.SortFunc(polls, func(a, b entity.Poll) int {
+		if c := a.ClosesAt.Compare(b.ClosesAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return polls, nil
+}
+
+// ListPolls returns up to limit polls matching filter, from the newest to the oldest,
+// with numbers of their voters. Only polls with ID less than beforeID are returned, unless it's 0.
+func (r *Repo) ListPolls(ctx context.Context, filter entity.PollFilter, beforeID uint64, limit int) ([]entity.PollSummary, error) {
+	const op = "repo.memory.ListPolls"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var polls []entity.Poll
+	for _, poll := range r.polls {
+		if beforeID != 0 && poll.ID >= beforeID {
+			continue
+		}
+		if !matchesFilter(poll, filter) {
+			continue
+		}
+		polls = append(polls, poll)
+	}
+
+	slices.SortFunc(polls, func(a, b entity.Poll) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+	if len(polls) > limit {
+		polls = polls[:limit]
+	}
+
+	summaries := make([]entity.PollSummary, 0, len(polls))
+	for _, poll := range polls {
+		voters := len(r.votes[poll.ID])
+		if poll.IsAnonymous {
+			voters = r.countBallots(poll.ID)
+		}
+		summaries = append(summaries, entity.PollSummary{Poll: poll, Voters: uint64(voters)})
+	}
+
+	return summaries, nil
+}
+
+// matchesFilter reports whether poll is selected by filter like by key of index in tarantool.
+func matchesFilter(poll entity.Poll, filter entity.PollFilter) bool {
+	if filter.Creator != "" {
+		return poll.Creator == filter.Creator
+	}
+	if poll.Channel != filter.Channel {
+		return false
+	}
+
+	switch filter.Status {
+	case entity.PollStatusOpen:
+		return !poll.IsFinished
+	case entity.PollStatusFinished:
+		return poll.IsFinished
+	}
+
+	return true
+}
+
+// SetPollPost saves ID of the post which announces the poll.
+func (r *Repo) SetPollPost(ctx context.Context, pollID uint64, postID string) error {
+	const op = "repo.memory.SetPollPost"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	poll, ok := r.polls[pollID]
+	if !ok {
+		return nil
+	}
+
+	poll.PostID = postID
+	r.polls[pollID] = poll
+
+	return nil
+}
+
+// SetResultsPost saves ID of the post with results of the finished poll.
+func (r *Repo) SetResultsPost(ctx context.Context, pollID uint64, postID string) error {
+	const op = "repo.memory.SetResultsPost"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	poll, ok := r.polls[pollID]
+	if !ok {
+		return nil
+	}
+
+	poll.ResultsPostID = postID
+	r.polls[pollID] = poll
+
+	return nil
+}
+
+// DeletePoll deletes the whole information about poll: votes, ballots, options, edits and poll itself.
+func (r *Repo) DeletePoll(ctx context.Context, pollID uint64) error {
+	const op = "repo.memory.DeletePoll"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.votes, pollID)
+	delete(r.anonymousVoters, pollID)
+	for ballotID, ballot := range r.ballots {
+		if ballot.PollID == pollID {
+			delete(r.ballots, ballotID)
+		}
+	}
+	delete(r.options, pollID)
+	delete(r.edits, pollID)
+	delete(r.polls, pollID)
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"sync"
+	"vote-bot/internal/entity"
+)
+
+// Repo keeps polls, options, votes, poll edits, cursors, processed commands and leases in process memory.
+//
+// It mirrors the behaviour of the tarantool repo (including the lua-defined
+// functions from init.lua), so it can be used in tests and for offline runs.
+type Repo struct {
+	mu sync.RWMutex
+
+	// Sequences of the ids, the same as poll_id, option_id, vote_id and poll_edit_id in tarantool.
+	pollSeq   uint64
+	optionSeq uint64
+	voteSeq   uint64
+	editSeq   uint64
+
+	polls   map[uint64]entity.Poll
+	options map[uint64][]entity.Option   // by poll id
+	votes   map[uint64][]entity.Vote     // by poll id
+	edits   map[uint64][]entity.PollEdit // by poll id
+
+	// Voters and ballots of anonymous polls are kept apart like in tarantool.
+	anonymousVoters map[uint64]map[string]struct{} // voter hashes by poll id
+	ballots         map[string]entity.Vote         // by ballot id
+
+	cursors  map[string]entity.Cursor           // by channel
+	commands map[string]entity.ProcessedCommand // by post id
+	leases   map[string]lease                   // by name
+}
+
+func NewRepo() *Repo {
+	return &Repo{
+		polls:   make(map[uint64]entity.Poll),
+		options: make(map[uint64][]entity.Option),
+		votes:   make(map[uint64][]entity.Vote),
+		edits:   make(map[uint64][]entity.PollEdit),
+
+		anonymousVoters: make(map[uint64]map[string]struct{}),
+		ballots:         make(map[string]entity.Vote),
+
+		cursors:  make(map[string]entity.Cursor),
+		commands: make(map[string]entity.ProcessedCommand),
+		leases:   make(map[string]lease),
+	}
+}
@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+)
+
+// CreateVote adds new vote record.
+//
+// In case user votes second time, his vote will simply be updated
+// the same way create_vote() does it in tarantool.
+func (r *Repo) CreateVote(ctx context.Context, vote entity.Vote) (*entity.Vote, error) {
+	const op = "repo.memory.CreateVote"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	votes := r.votes[vote.PollID]
+	for i := range votes {
+		if votes[i].User == vote.User {
+			votes[i].OptionIDs = slices.Clone(vote.OptionIDs)
+			votes[i].Scores = slices.Clone(vote.Scores)
+			newVote := cloneVote(votes[i])
+			return &newVote, nil
+		}
+	}
+
+	r.voteSeq++
+	newVote := entity.Vote{
+		VoteID:    r.voteSeq,
+		PollID:    vote.PollID,
+		OptionIDs: slices.Clone(vote.OptionIDs),
+		User:      vote.User,
+		Scores:    slices.Clone(vote.Scores),
+	}
+	r.votes[vote.PollID] = append(votes, newVote)
+
+	newVote = cloneVote(newVote)
+	return &newVote, nil
+}
+
+// GetVotes returns all votes that belong to poll with pollID.
+func (r *Repo) GetVotes(ctx context.Context, pollID uint64) ([]entity.Vote, error) {
+	const op = "repo.memory.GetVotes"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	votes := make([]entity.Vote, 0, len(r.votes[pollID]))
+	for _, vote := range r.votes[pollID] {
+		votes = append(votes, cloneVote(vote))
+	}
+
+	// Ballots of anonymous poll are sorted by id like in ballot_poll_id index.
+	ballotIDs := make([]string, 0)
+	for ballotID, ballot := range r.ballots {
+		if ballot.PollID == pollID {
+			ballotIDs = append(ballotIDs, ballotID)
+		}
+	}
+	slices.Sort(ballotIDs)
+	for _, ballotID := range ballotIDs {
+		votes = append(votes, cloneVote(r.ballots[ballotID]))
+	}
+
+	return votes, nil
+}
+
+// CreateAnonymousVote saves vote in anonymous poll.
+//
+// voterHash prevents voting twice, while option numbers and scores are saved under ballotID.
+// In case voter has already voted, repo.ErrVoterExists is returned.
+func (r *Repo) CreateAnonymousVote(ctx context.Context, pollID uint64, voterHash, ballotID string, optionIDs, scores []uint64) (*entity.Vote, error) {
+	const op = "repo.memory.CreateAnonymousVote"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.anonymousVoters[pollID][voterHash]; ok {
+		return nil, fmt.Errorf("%s: %w", op, repo.ErrVoterExists)
+	}
+	if r.anonymousVoters[pollID] == nil {
+		r.anonymousVoters[pollID] = make(map[string]struct{})
+	}
+	r.anonymousVoters[pollID][voterHash] = struct{}{}
+
+	ballot := entity.Vote{
+		PollID:    pollID,
+		OptionIDs: slices.Clone(optionIDs),
+		Scores:    slices.Clone(scores),
+	}
+	r.ballots[ballotID] = ballot
+
+	ballot = cloneVote(ballot)
+	return &ballot, nil
+}
+
+// DeleteAnonymousVote removes voter and his ballot from anonymous poll.
+// Ballot of another poll isn't removed.
+//
+// (false, nil) indicates that user hasn't voted before.
+func (r *Repo) DeleteAnonymousVote(ctx context.Context, pollID uint64, voterHash, ballotID string) (isDeleted bool, err error) {
+	const op = "repo.memory.DeleteAnonymousVote"
+
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.anonymousVoters[pollID][voterHash]; !ok {
+		return false, nil
+	}
+	delete(r.anonymousVoters[pollID], voterHash)
+
+	if ballot, ok := r.ballots[ballotID]; ok && ballot.PollID == pollID {
+		delete(r.ballots, ballotID)
+	}
+
+	return true, nil
+}
+
+// GetVote returns vote of the user in poll with pollID.
+func (r *Repo) GetVote(ctx context.Context, user string, pollID uint64) (*entity.Vote, error) {
+	const op = "repo.memory.GetVote"
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, vote := range r.votes[pollID] {
+		if vote.User == user {
+			vote = cloneVote(vote)
+			return &vote, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %w", op, repo.ErrVoteDoesNotExist)
+}
+
+// DeleteVote removes user's vote in the poll.
+//
+// (false, nil) indicates that user hasn't voted before.
+func (r *Repo) DeleteVote(ctx context.Context, user string, pollID uint64) (isDeleted bool, err error) {
+	const op = "repo.memory.DeleteVote"
+
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	votes := r.votes[pollID]
+	for i := range votes {
+		if votes[i].User == user {
+			r.votes[pollID] = slices.Delete(votes, i, i+1)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func cloneVote(vote entity.Vote) entity.Vote {
+	vote.OptionIDs = slices.Clone(vote.OptionIDs)
+	vote.Scores = slices.Clone(vote.Scores)
+	return vote
+}
+
+// countBallots returns number of ballots in anonymous poll. Caller must hold the lock.
+func (r *Repo) countBallots(pollID uint64) int {
+	count := 0
+	for _, ballot := range r.ballots {
+		if ballot.PollID == pollID {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,103 @@
+package tarantool
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"vote-bot/internal/entity"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+const (
+	claimCommandFunc           = "claim_command"
+	deleteProcessedCommandFunc = "delete_processed_commands"
+)
+
+// ClaimCommand saves command with processing status in case its post wasn't handled yet.
+// Otherwise, it returns the command saved before.
+// It uses lua-defined claim_command() func under the hood, so only one bot replica claims the post.
+func (r *Repo) ClaimCommand(ctx context.Context, cmd entity.ProcessedCommand) (*entity.ProcessedCommand, error) {
+	const op = "repo.tarantool.ClaimCommand"
+
+	data, err := r.conn.Do(
+		tarantool.NewCall17Request(claimCommandFunc).
+			Args([]any{cmd.PostID, cmd.Channel, timeToTuple(cmd.ProcessedAt)}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to claim command: %w", op, err)
+	}
+
+	commands := serializeCommands(data)
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	return &commands[0], nil
+}
+
+// SaveCommand replaces the command with its outcome.
+func (r *Repo) SaveCommand(ctx context.Context, cmd entity.ProcessedCommand) error {
+	const op = "repo.tarantool.SaveCommand"
+
+	_, err := r.conn.Do(
+		tarantool.NewReplaceRequest(commandSpace).
+			Tuple([]any{
+				cmd.PostID, cmd.Channel, timeToTuple(cmd.ProcessedAt),
+				string(cmd.Status), cmd.Response, cmd.Public,
+			}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("%s: failed to save command: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteCommandsBefore deletes commands processed before the time and returns their number.
+// It uses lua-defined delete_processed_commands() func under the hood.
+func (r *Repo) DeleteCommandsBefore(ctx context.Context, before time.Time) (uint64, error) {
+	const op = "repo.tarantool.DeleteCommandsBefore"
+
+	data, err := r.conn.Do(
+		tarantool.NewCall17Request(deleteProcessedCommandFunc).
+			Args([]any{timeToTuple(before)}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to delete commands: %w", op, err)
+	}
+
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	return toUint64(data[0]), nil
+}
+
+func serializeCommands(tuples []any) []entity.ProcessedCommand {
+	commands := make([]entity.ProcessedCommand, 0, len(tuples))
+
+	for _, el := range tuples {
+		// Lua function returns nil in case there is no command.
+		tuple, ok := el.([]any)
+		if !ok {
+			continue
+		}
+
+		cmd := entity.ProcessedCommand{
+			PostID:      tuple[0].(string),
+			Channel:     tuple[1].(string),
+			ProcessedAt: timeFromTuple(tuple[2]),
+			Status:      entity.CommandStatus(tuple[3].(string)),
+		}
+		cmd.Response, _ = field(tuple, 4).(string)
+		cmd.Public, _ = field(tuple, 5).(bool)
+
+		commands = append(commands, cmd)
+	}
+
+	return commands
+}
@@ -0,0 +1,62 @@
+package tarantool
+
+import (
+	"context"
+	"fmt"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// GetCursor returns high-water mark of handled posts in the channel.
+func (r *Repo) GetCursor(ctx context.Context, channel string) (*entity.Cursor, error) {
+	const op = "repo.tarantool.GetCursor"
+
+	data, err := r.conn.Do(
+		tarantool.NewSelectRequest(cursorSpace).
+			Key([]any{channel}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get cursor: %w", op, err)
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, repo.ErrCursorDoesNotExist)
+	}
+
+	tuple := data[0].([]any)
+	cursor := &entity.Cursor{
+		Channel:    tuple[0].(string),
+		LastPostAt: int64(toUint64(tuple[1])),
+	}
+	if ids, ok := tuple[2].([]any); ok {
+		for _, id := range ids {
+			cursor.PostIDs = append(cursor.PostIDs, id.(string))
+		}
+	}
+
+	return cursor, nil
+}
+
+// SaveCursor creates or replaces the cursor of the channel.
+func (r *Repo) SaveCursor(ctx context.Context, cursor entity.Cursor) error {
+	const op = "repo.tarantool.SaveCursor"
+
+	postIDs := cursor.PostIDs
+	if postIDs == nil {
+		postIDs = []string{}
+	}
+
+	_, err := r.conn.Do(
+		tarantool.NewReplaceRequest(cursorSpace).
+			Tuple([]any{cursor.Channel, uint64(cursor.LastPostAt), postIDs}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("%s: failed to save cursor: %w", op, err)
+	}
+
+	return nil
+}
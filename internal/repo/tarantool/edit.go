@@ -0,0 +1,290 @@
+package tarantool
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"vote-bot/internal/entity"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+const (
+	addOptionFunc       = "add_option"
+	renameOptionFunc    = "rename_option"
+	renumberOptionsFunc = "renumber_options"
+	deleteEditsFunc     = "delete_poll_edits"
+	getEditsIndex       = "poll_edit_poll_id"
+)
+
+// RenamePoll changes name of the poll to edit.NewValue and saves the edit within stream (transaction).
+//
+// Begin, commit and rollback of edits don't depend on ctx, so that transaction isn't left open when ctx is done.
+func (r *Repo) RenamePoll(ctx context.Context, edit entity.PollEdit) error {
+	const op = "repo.tarantool.RenamePoll"
+
+	stream, err := r.beginTxn()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stream.Do(
+		tarantool.NewUpdateRequest(pollSpace).
+			Key(tarantool.UintKey{I: uint(edit.PollID)}).
+			Operations(tarantool.NewOperations().Assign(pollNameField, edit.NewValue)).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return rollback(stream, fmt.Errorf("%s: failed to rename poll: %w", op, err))
+	}
+
+	if err := saveEdit(ctx, stream, edit); err != nil {
+		return rollback(stream, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if err := commit(stream); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReopenPoll makes the finished poll open with deadline closesAt, records who reopened it
+// and saves the edit within stream (transaction). Post with results of the poll is forgotten.
+func (r *Repo) ReopenPoll(ctx context.Context, edit entity.PollEdit, closesAt time.Time) error {
+	const op = "repo.tarantool.ReopenPoll"
+
+	stream, err := r.beginTxn()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = stream.Do(
+		tarantool.NewUpdateRequest(pollSpace).
+			Key(tarantool.UintKey{I: uint(edit.PollID)}).
+			Operations(tarantool.NewOperations().
+				Assign(pollIsFinishedField, false).
+				Assign(pollClosesAtField, timeToTuple(closesAt)).
+				Assign(pollResultsField, nil).
+				Assign(pollReopenedByField, edit.Editor).
+				Assign(pollReopenedAtField, timeToTuple(edit.EditedAt))).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return rollback(stream, fmt.Errorf("%s: failed to reopen poll: %w", op, err))
+	}
+
+	if err := saveEdit(ctx, stream, edit); err != nil {
+		return rollback(stream, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if err := commit(stream); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AddOption adds option named edit.NewValue to the poll and saves the edit within stream (transaction).
+// Option gets the next number, it's saved to the edit as well.
+// It uses lua-defined add_option() func under the hood.
+func (r *Repo) AddOption(ctx context.Context, edit entity.PollEdit) (*entity.Option, error) {
+	const op = "repo.tarantool.AddOption"
+
+	stream, err := r.beginTxn()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var options []entity.Option
+	err = stream.Do(
+		tarantool.NewCall17Request(addOptionFunc).
+			Args([]any{edit.PollID, edit.NewValue}).
+			Context(ctx),
+	).GetTyped(&options)
+	if err != nil {
+		return nil, rollback(stream, fmt.Errorf("%s: failed to add option: %w", op, err))
+	}
+	if len(options) == 0 {
+		return nil, rollback(stream, fmt.Errorf("%s: option wasn't returned", op))
+	}
+
+	edit.OptionNum = options[0].Num
+	if err := saveEdit(ctx, stream, edit); err != nil {
+		return nil, rollback(stream, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if err := commit(stream); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &options[0], nil
+}
+
+// RenameOption changes name of option edit.OptionNum to edit.NewValue and saves the edit within stream (transaction).
+// It uses lua-defined rename_option() func under the hood.
+func (r *Repo) RenameOption(ctx context.Context, edit entity.PollEdit) error {
+	const op = "repo.tarantool.RenameOption"
+
+	stream, err := r.beginTxn()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, err := stream.Do(
+		tarantool.NewCall17Request(renameOptionFunc).
+			Args([]any{edit.PollID, edit.OptionNum, edit.NewValue}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return rollback(stream, fmt.Errorf("%s: failed to rename option: %w", op, err))
+	}
+	if renamed, _ := field(data, 0).(bool); !renamed {
+		return rollback(stream, fmt.Errorf("%s: option %d not found", op, edit.OptionNum))
+	}
+
+	if err := saveEdit(ctx, stream, edit); err != nil {
+		return rollback(stream, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if err := commit(stream); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RenumberOptions gives option with number i+1 number nums[i], options with zero number are removed.
+// Options in votes and ballots are renumbered too and the edit is saved, all within stream (transaction).
+// It returns votes which lost options, ones left without options are deleted.
+// It uses lua-defined renumber_options() func under the hood.
+func (r *Repo) RenumberOptions(ctx context.Context, edit entity.PollEdit, nums []uint64) ([]entity.Vote, error) {
+	const op = "repo.tarantool.RenumberOptions"
+
+	stream, err := r.beginTxn()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	data, err := stream.Do(
+		tarantool.NewCall17Request(renumberOptionsFunc).
+			Args([]any{edit.PollID, nums}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, rollback(stream, fmt.Errorf("%s: failed to renumber options: %w", op, err))
+	}
+
+	if err := saveEdit(ctx, stream, edit); err != nil {
+		return nil, rollback(stream, fmt.Errorf("%s: %w", op, err))
+	}
+
+	if err := commit(stream); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Function returns the only value which is array of changed votes.
+	if len(data) == 0 {
+		return nil, nil
+	}
+	tuples, _ := data[0].([]any)
+
+	return serializeVotes(tuples), nil
+}
+
+// GetPollEdits returns edits of the poll in order they were made.
+func (r *Repo) GetPollEdits(ctx context.Context, pollID uint64) ([]entity.PollEdit, error) {
+	const op = "repo.tarantool.GetPollEdits"
+
+	data, err := r.conn.Do(
+		tarantool.NewSelectRequest(editSpace).
+			Index(getEditsIndex).
+			Key([]any{int(pollID)}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get poll edits: %w", op, err)
+	}
+
+	return serializeEdits(data), nil
+}
+
+// saveEdit inserts the edit to editSpace inside of stream (txn).
+func saveEdit(ctx context.Context, s *tarantool.Stream, edit entity.PollEdit) error {
+	var optionNum any
+	if edit.OptionNum != 0 {
+		optionNum = edit.OptionNum
+	}
+
+	tuple := []any{
+		nil, edit.PollID, edit.Editor, uint64(edit.EditedAt.Unix()), string(edit.Action),
+		optionNum, edit.OldValue, edit.NewValue,
+	}
+	_, err := s.Do(
+		tarantool.NewInsertRequest(editSpace).
+			Tuple(tuple).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("failed to save poll edit: %w", err)
+	}
+
+	return nil
+}
+
+// beginTxn opens stream and begins transaction in it.
+// Interactive transactions require mvcc engine to be enabled in tarantool.
+func (r *Repo) beginTxn() (*tarantool.Stream, error) {
+	stream, err := r.conn.NewStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to init stream for txn: %w", err)
+	}
+
+	if _, err := stream.Do(tarantool.NewBeginRequest()).Get(); err != nil {
+		return nil, fmt.Errorf("failed to begin txn: %w", err)
+	}
+
+	return stream, nil
+}
+
+// rollback rolls back transaction of the stream which failed with err.
+func rollback(s *tarantool.Stream, err error) error {
+	if _, er := s.Do(tarantool.NewRollbackRequest()).Get(); er != nil {
+		return fmt.Errorf("%w (failed to roll back txn: %w)", err, er)
+	}
+
+	return err
+}
+
+// commit commits transaction of the stream.
+func commit(s *tarantool.Stream) error {
+	if _, err := s.Do(tarantool.NewCommitRequest()).Get(); err != nil {
+		return fmt.Errorf("failed to commit txn: %w", err)
+	}
+
+	return nil
+}
+
+// Converts tarantool response with tuples of space "poll_edits" to []entity.PollEdit.
+func serializeEdits(tuples []any) []entity.PollEdit {
+	edits := make([]entity.PollEdit, 0, len(tuples))
+
+	for _, el := range tuples {
+		tuple := el.([]any)
+
+		edit := entity.PollEdit{
+			ID:       toUint64(tuple[0]),
+			PollID:   toUint64(tuple[1]),
+			Editor:   tuple[2].(string),
+			EditedAt: time.Unix(int64(toUint64(tuple[3])), 0),
+			Action:   entity.EditAction(tuple[4].(string)),
+		}
+		edit.OptionNum = toUint64(field(tuple, 5))
+		edit.OldValue, _ = field(tuple, 6).(string)
+		edit.NewValue, _ = field(tuple, 7).(string)
+
+		edits = append(edits, edit)
+	}
+
+	return edits
+}
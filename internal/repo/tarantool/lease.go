@@ -0,0 +1,54 @@
+package tarantool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+const (
+	acquireLeaseFunc = "acquire_lease"
+	releaseLeaseFunc = "release_lease"
+)
+
+// AcquireLease takes the lease for ttl in case it's free, expired or already held by the holder.
+// It uses lua-defined acquire_lease() func under the hood, which checks expiration by tarantool clock,
+// so clocks of bot replicas don't need to be in sync.
+func (r *Repo) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	const op = "repo.tarantool.AcquireLease"
+
+	data, err := r.conn.Do(
+		tarantool.NewCall17Request(acquireLeaseFunc).
+			Args([]any{name, holder, uint64(ttl.Milliseconds())}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return false, fmt.Errorf("%s: failed to acquire lease: %w", op, err)
+	}
+
+	if len(data) == 0 {
+		return false, nil
+	}
+	acquired, _ := data[0].(bool)
+
+	return acquired, nil
+}
+
+// ReleaseLease frees the lease in case it's held by the holder.
+// It uses lua-defined release_lease() func under the hood.
+func (r *Repo) ReleaseLease(ctx context.Context, name string, holder string) error {
+	const op = "repo.tarantool.ReleaseLease"
+
+	_, err := r.conn.Do(
+		tarantool.NewCall17Request(releaseLeaseFunc).
+			Args([]any{name, holder}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("%s: failed to release lease: %w", op, err)
+	}
+
+	return nil
+}
@@ -1,7 +1,10 @@
 package tarantool
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"slices"
 	"vote-bot/internal/entity"
 	"vote-bot/internal/repo"
 
@@ -12,8 +15,8 @@ const (
 	optionPollIndex = "option_poll_id"
 )
 
-// GetOptions returns all options that belong to poll with pollID.
-func (r *Repo) GetOptions(pollID uint64) ([]entity.Option, error) {
+// GetOptions returns all options that belong to poll with pollID sorted by their numbers.
+func (r *Repo) GetOptions(ctx context.Context, pollID uint64) ([]entity.Option, error) {
 	const op = "repo.tarantool.GetOptions"
 
 	var options []entity.Option
@@ -21,7 +24,8 @@ func (r *Repo) GetOptions(pollID uint64) ([]entity.Option, error) {
 	err := r.conn.Do(
 		tarantool.NewSelectRequest(optionSpace).
 			Index(optionPollIndex).
-			Key([]any{int(pollID)}),
+			Key([]any{int(pollID)}).
+			Context(ctx),
 	).GetTyped(&options)
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to get options: %w", op, err)
@@ -31,5 +35,10 @@ func (r *Repo) GetOptions(pollID uint64) ([]entity.Option, error) {
 		return nil, fmt.Errorf("%s: %w", op, repo.ErrNoOptionsFound)
 	}
 
+	// Index is ordered by option ID, and options may be renumbered after the poll is edited.
+	slices.SortFunc(options, func(a, b entity.Option) int {
+		return cmp.Compare(a.Num, b.Num)
+	})
+
 	return options, nil
 }
@@ -1,6 +1,7 @@
 package tarantool
 
 import (
+	"context"
 	"fmt"
 	"vote-bot/internal/entity"
 	"vote-bot/internal/repo"
@@ -9,24 +10,35 @@ import (
 )
 
 const (
-	deleteVotesFunc   = "delete_votes"
-	deleteOptionsFunc = "delete_options"
+	deleteVotesFunc     = "delete_votes"
+	deleteBallotsFunc   = "delete_anonymous_votes"
+	deleteOptionsFunc   = "delete_options"
+	getPendingPollsFunc = "get_pending_polls"
+	listPollsFunc       = "list_polls"
+)
+
+// Indexes of polls space used to list polls.
+const (
+	pollChannelIndex       = "poll_channel_id"
+	pollChannelStatusIndex = "poll_channel_status_id"
+	pollCreatorIndex       = "poll_creator_id"
 )
 
 // CreatePollWithOprions inserts new poll and its options to pollSpace and optionSpace respectively.
 //
 // Uses createPoll and createOptions under the hood within stream (transaction).
-func (r *Repo) CreatePollWithOptions(poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error) {
+// Commit and rollback don't depend on ctx, so that transaction isn't left open when ctx is done.
+func (r *Repo) CreatePollWithOptions(ctx context.Context, poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error) {
 	const op = "repo.tarantool.CreatePollWithOprions"
 
-	// Create new stream for atomicity.
-	stream, err := r.conn.NewStream()
+	// Poll and options are created in one transaction for atomicity.
+	stream, err := r.beginTxn()
 	if err != nil {
-		return nil, nil, fmt.Errorf("%s: failed to init stream for txn: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	// Create poll within stream.
-	newPoll, err := createPoll(stream, poll)
+	newPoll, err := createPoll(ctx, stream, poll)
 	if err != nil {
 		_, er := stream.Do(
 			tarantool.NewRollbackRequest(),
@@ -45,7 +57,7 @@ func (r *Repo) CreatePollWithOptions(poll entity.Poll, options []entity.Option)
 	}
 
 	// Create options within stream.
-	newOptions, err := createOptions(stream, options)
+	newOptions, err := createOptions(ctx, stream, options)
 	if err != nil {
 		_, er := stream.Do(
 			tarantool.NewRollbackRequest(),
@@ -68,27 +80,32 @@ func (r *Repo) CreatePollWithOptions(poll entity.Poll, options []entity.Option)
 }
 
 // createPoll inserts a new poll to pollSpace inside of stream (txn).
-func createPoll(s *tarantool.Stream, poll entity.Poll) (*entity.Poll, error) {
+func createPoll(ctx context.Context, s *tarantool.Stream, poll entity.Poll) (*entity.Poll, error) {
 	const op = "repo.tarantool.createPoll"
 
-	// Plural form because tarantool query returns slice of tuples
-	// but only the first one is needed.
-	var newPolls []entity.Poll
-
-	tuple := []any{nil, poll.Name, poll.Creator, poll.Channel, nil, poll.IsMultiVote}
-	err := s.Do(
+	tuple := []any{
+		nil, poll.Name, poll.Creator, poll.Channel, false, string(poll.Type),
+		nil, timeToTuple(poll.ClosesAt), poll.IsAnonymous, poll.Salt, poll.MaxChoices, poll.ShowVoters,
+		nil, nil, nil,
+	}
+	data, err := s.Do(
 		tarantool.NewInsertRequest(pollSpace).
-			Tuple(tuple),
-	).GetTyped(&newPolls)
+			Tuple(tuple).
+			Context(ctx),
+	).Get()
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to create poll: %w", op, err)
 	}
 
+	// Plural form because tarantool query returns slice of tuples
+	// but only the first one is needed.
+	newPolls := serializePolls(data)
+
 	return &newPolls[0], nil
 }
 
 // createOptions inserts new options to optionSpace inside of stream (txn).
-func createOptions(s *tarantool.Stream, options []entity.Option) ([]entity.Option, error) {
+func createOptions(ctx context.Context, s *tarantool.Stream, options []entity.Option) ([]entity.Option, error) {
 	const op = "repo.tarantool.createOptions"
 
 	newOptions := make([]entity.Option, 0, len(options))
@@ -96,7 +113,7 @@ func createOptions(s *tarantool.Stream, options []entity.Option) ([]entity.Optio
 	var futures []*tarantool.Future
 	for i, option := range options {
 		tuple := []any{nil, int(option.PollID), option.Name, i + 1}
-		request := tarantool.NewInsertRequest(optionSpace).Tuple(tuple)
+		request := tarantool.NewInsertRequest(optionSpace).Tuple(tuple).Context(ctx)
 		futures = append(futures, s.Do(request))
 	}
 
@@ -114,22 +131,22 @@ func createOptions(s *tarantool.Stream, options []entity.Option) ([]entity.Optio
 	return newOptions, nil
 }
 
-
 // GetPoll returns info about poll by its ID.
-func (r *Repo) GetPoll(pollID uint64) (*entity.Poll, error) {
+func (r *Repo) GetPoll(ctx context.Context, pollID uint64) (*entity.Poll, error) {
 	const op = "repo.tarantool.GetPoll"
 
-	// Plural form because tarantool query returns slice of tuples
-	// but only the first one is needed.
-	var polls []entity.Poll
-
-	err := r.conn.Do(
+	data, err := r.conn.Do(
 		tarantool.NewSelectRequest(pollSpace).
-			Key(tarantool.UintKey{I: uint(pollID)}),
-	).GetTyped(&polls)
+			Key(tarantool.UintKey{I: uint(pollID)}).
+			Context(ctx),
+	).Get()
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to get poll by ID: %w", op, err)
 	}
+
+	// Plural form because tarantool query returns slice of tuples
+	// but only the first one is needed.
+	polls := serializePolls(data)
 	if len(polls) == 0 {
 		return nil, fmt.Errorf("%s: %w", op, repo.ErrPollDoesNotExist)
 	}
@@ -138,13 +155,14 @@ func (r *Repo) GetPoll(pollID uint64) (*entity.Poll, error) {
 }
 
 // FinishPoll finishes the poll by setting is_finished field to true.
-func (r *Repo) FinishPoll(pollID uint64) error {
+func (r *Repo) FinishPoll(ctx context.Context, pollID uint64) error {
 	const op = "repo.tarantool.FinishPoll"
 
 	_, err := r.conn.Do(
 		tarantool.NewUpdateRequest(pollSpace).
 			Key(tarantool.UintKey{I: uint(pollID)}).
-			Operations(tarantool.NewOperations().Assign(4, true)),
+			Operations(tarantool.NewOperations().Assign(pollIsFinishedField, true)).
+			Context(ctx),
 	).Get()
 	if err != nil {
 		return fmt.Errorf("%s: failed to finish poll: %w", op, err)
@@ -153,21 +171,134 @@ func (r *Repo) FinishPoll(pollID uint64) error {
 	return nil
 }
 
-// DeletePoll deletes the whole information about poll from spaces: votes, options, polls.
-// It uses lua-defined functions delete_votes() and delete_options() under the hood.
-func (r *Repo) DeletePoll(pollID uint64) error {
+// GetPendingPolls returns polls which have a deadline and are not finished yet.
+// It uses lua-defined get_pending_polls() func under the hood.
+func (r *Repo) GetPendingPolls(ctx context.Context) ([]entity.Poll, error) {
+	const op = "repo.tarantool.GetPendingPolls"
+
+	data, err := r.conn.Do(
+		tarantool.NewCall17Request(getPendingPollsFunc).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get pending polls: %w", op, err)
+	}
+
+	// Function returns the only value which is array of tuples.
+	if len(data) == 0 {
+		return nil, nil
+	}
+	tuples, _ := data[0].([]any)
+
+	return serializePolls(tuples), nil
+}
+
+// ListPolls returns up to limit polls matching filter, from the newest to the oldest,
+// with numbers of their voters. Only polls with ID less than beforeID are returned, unless it's 0.
+// It uses lua-defined list_polls() func under the hood.
+func (r *Repo) ListPolls(ctx context.Context, filter entity.PollFilter, beforeID uint64, limit int) ([]entity.PollSummary, error) {
+	const op = "repo.tarantool.ListPolls"
+
+	var (
+		index string
+		key   []any
+	)
+	switch {
+	case filter.Creator != "":
+		index, key = pollCreatorIndex, []any{filter.Creator}
+	case filter.Status == entity.PollStatusOpen:
+		index, key = pollChannelStatusIndex, []any{filter.Channel, false}
+	case filter.Status == entity.PollStatusFinished:
+		index, key = pollChannelStatusIndex, []any{filter.Channel, true}
+	default:
+		index, key = pollChannelIndex, []any{filter.Channel}
+	}
+
+	var before any
+	if beforeID != 0 {
+		before = beforeID
+	}
+
+	data, err := r.conn.Do(
+		tarantool.NewCall17Request(listPollsFunc).
+			Args([]any{index, key, before, limit}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to list polls: %w", op, err)
+	}
+
+	// Function returns the only value which is array of pairs of poll and number of voters.
+	if len(data) == 0 {
+		return nil, nil
+	}
+	pairs, _ := data[0].([]any)
+
+	summaries := make([]entity.PollSummary, 0, len(pairs))
+	for _, el := range pairs {
+		pair := el.([]any)
+		summaries = append(summaries, entity.PollSummary{
+			Poll:   serializePolls(pair[:1])[0],
+			Voters: toUint64(pair[1]),
+		})
+	}
+
+	return summaries, nil
+}
+
+// SetPollPost saves ID of the post which announces the poll.
+func (r *Repo) SetPollPost(ctx context.Context, pollID uint64, postID string) error {
+	const op = "repo.tarantool.SetPollPost"
+
+	_, err := r.conn.Do(
+		tarantool.NewUpdateRequest(pollSpace).
+			Key(tarantool.UintKey{I: uint(pollID)}).
+			Operations(tarantool.NewOperations().Assign(pollPostIDField, postID)).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("%s: failed to set poll post: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetResultsPost saves ID of the post with results of the finished poll.
+func (r *Repo) SetResultsPost(ctx context.Context, pollID uint64, postID string) error {
+	const op = "repo.tarantool.SetResultsPost"
+
+	_, err := r.conn.Do(
+		tarantool.NewUpdateRequest(pollSpace).
+			Key(tarantool.UintKey{I: uint(pollID)}).
+			Operations(tarantool.NewOperations().Assign(pollResultsField, postID)).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return fmt.Errorf("%s: failed to set results post: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeletePoll deletes the whole information about poll from spaces: votes, anonymous_voters, ballots, options, poll_edits, polls.
+// It uses lua-defined functions delete_votes(), delete_anonymous_votes(), delete_options()
+// and delete_poll_edits() under the hood.
+//
+// Commit and rollback don't depend on ctx, so that transaction isn't left open when ctx is done.
+func (r *Repo) DeletePoll(ctx context.Context, pollID uint64) error {
 	const op = "repo.tarantool.DeletePoll"
 
-	// Starts stream.
-	stream, err := r.conn.NewStream()
+	// All deletions are made in one transaction, so that poll isn't left half-deleted.
+	stream, err := r.beginTxn()
 	if err != nil {
-		return fmt.Errorf("%s: failed to init stream for txn: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	// Delete votes using delete_votes() func.
 	_, err = stream.Do(
 		tarantool.NewCall17Request(deleteVotesFunc).
-			Args([]any{pollID}),
+			Args([]any{pollID}).
+			Context(ctx),
 	).Get()
 	if err != nil {
 		_, er := stream.Do(
@@ -180,10 +311,28 @@ func (r *Repo) DeletePoll(pollID uint64) error {
 		return fmt.Errorf("%s: failed to delete votes: %w", op, err)
 	}
 
+	// Delete votes of anonymous poll using delete_anonymous_votes() func.
+	_, err = stream.Do(
+		tarantool.NewCall17Request(deleteBallotsFunc).
+			Args([]any{pollID}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		_, er := stream.Do(
+			tarantool.NewRollbackRequest(),
+		).Get()
+		if er != nil {
+			return fmt.Errorf("%s: failed to roll back DeletePoll txn: %w", op, er)
+		}
+
+		return fmt.Errorf("%s: failed to delete anonymous votes: %w", op, err)
+	}
+
 	// Delete options using delete_options() func.
 	_, err = stream.Do(
 		tarantool.NewCall17Request(deleteOptionsFunc).
-			Args([]any{pollID}),
+			Args([]any{pollID}).
+			Context(ctx),
 	).Get()
 	if err != nil {
 		_, er := stream.Do(
@@ -196,10 +345,28 @@ func (r *Repo) DeletePoll(pollID uint64) error {
 		return fmt.Errorf("%s: failed to delete options: %w", op, err)
 	}
 
+	// Delete history of the poll using delete_poll_edits() func.
+	_, err = stream.Do(
+		tarantool.NewCall17Request(deleteEditsFunc).
+			Args([]any{pollID}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		_, er := stream.Do(
+			tarantool.NewRollbackRequest(),
+		).Get()
+		if er != nil {
+			return fmt.Errorf("%s: failed to roll back DeletePoll txn: %w", op, er)
+		}
+
+		return fmt.Errorf("%s: failed to delete poll edits: %w", op, err)
+	}
+
 	// Finally, delete poll itself.
 	_, err = stream.Do(
 		tarantool.NewDeleteRequest(pollSpace).
-			Key([]any{uint(pollID)}),
+			Key([]any{uint(pollID)}).
+			Context(ctx),
 	).Get()
 	if err != nil {
 		_, er := stream.Do(
@@ -222,3 +389,34 @@ func (r *Repo) DeletePoll(pollID uint64) error {
 
 	return nil
 }
+
+// Converts tarantool response of type []any to []entity.Poll.
+func serializePolls(tuples []any) []entity.Poll {
+	polls := make([]entity.Poll, 0, len(tuples))
+
+	for _, el := range tuples {
+		tuple := el.([]any)
+
+		poll := entity.Poll{
+			ID:         toUint64(tuple[0]),
+			Name:       tuple[1].(string),
+			Creator:    tuple[2].(string),
+			Channel:    tuple[3].(string),
+			IsFinished: tuple[4].(bool),
+			Type:       entity.PollType(tuple[5].(string)),
+		}
+		poll.PostID, _ = field(tuple, 6).(string)
+		poll.ClosesAt = timeFromTuple(field(tuple, 7))
+		poll.IsAnonymous, _ = field(tuple, 8).(bool)
+		poll.Salt, _ = field(tuple, 9).(string)
+		poll.MaxChoices = toUint64(field(tuple, 10))
+		poll.ShowVoters, _ = field(tuple, 11).(bool)
+		poll.ResultsPostID, _ = field(tuple, 12).(string)
+		poll.ReopenedBy, _ = field(tuple, 13).(string)
+		poll.ReopenedAt = timeFromTuple(field(tuple, 14))
+
+		polls = append(polls, poll)
+	}
+
+	return polls
+}
@@ -1,13 +1,30 @@
 package tarantool
 
 import (
+	"time"
+
 	"github.com/tarantool/go-tarantool/v2"
 )
 
 const (
-	pollSpace   = "polls"
-	optionSpace = "options"
-	voteSpace   = "votes"
+	pollSpace    = "polls"
+	optionSpace  = "options"
+	voteSpace    = "votes"
+	ballotSpace  = "ballots"
+	cursorSpace  = "channel_cursors"
+	commandSpace = "processed_commands"
+	editSpace    = "poll_edits"
+)
+
+// Field numbers of polls space (starting from 0) used in update requests.
+const (
+	pollIsFinishedField = 4
+	pollPostIDField     = 6
+	pollClosesAtField   = 7
+	pollNameField       = 1
+	pollResultsField    = 12
+	pollReopenedByField = 13
+	pollReopenedAtField = 14
 )
 
 // Repo wraps a Tarantool connection to abstract database interactions.
@@ -18,3 +35,88 @@ type Repo struct {
 func NewRepo(conn *tarantool.Connection) *Repo {
 	return &Repo{conn: conn}
 }
+
+// toUint64 converts integer from tarantool response to uint64.
+// msgpack decodes integers to the smallest type they fit in.
+func toUint64(v any) uint64 {
+	switch n := v.(type) {
+	case int8:
+		return uint64(n)
+	case int16:
+		return uint64(n)
+	case int32:
+		return uint64(n)
+	case int64:
+		return uint64(n)
+	case uint8:
+		return uint64(n)
+	case uint16:
+		return uint64(n)
+	case uint32:
+		return uint64(n)
+	case uint64:
+		return n
+	case int:
+		return uint64(n)
+	case uint:
+		return uint64(n)
+	}
+
+	return 0
+}
+
+// field returns tuple field by its index or nil in case tuple is shorter.
+// Tuples created before nullable field was added don't contain it.
+func field(tuple []any, i int) any {
+	if i >= len(tuple) {
+		return nil
+	}
+
+	return tuple[i]
+}
+
+// uintsFromTuple converts array from tarantool response to []uint64.
+// Null is converted to nil slice.
+func uintsFromTuple(v any) []uint64 {
+	arr, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+
+	nums := make([]uint64, 0, len(arr))
+	for _, el := range arr {
+		nums = append(nums, toUint64(el))
+	}
+
+	return nums
+}
+
+// uintsToTuple converts slice to array stored in tarantool.
+// Empty slice is stored as null.
+func uintsToTuple(nums []uint64) any {
+	if len(nums) == 0 {
+		return nil
+	}
+
+	return nums
+}
+
+// timeToTuple converts time to unix seconds stored in tarantool.
+// Zero time is stored as null.
+func timeToTuple(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return uint64(t.Unix())
+}
+
+// timeFromTuple converts unix seconds from tarantool to time.
+// Null is converted to zero time.
+func timeFromTuple(v any) time.Time {
+	if v == nil {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(toUint64(v)), 0)
+}
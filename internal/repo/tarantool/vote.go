@@ -1,8 +1,10 @@
 package tarantool
 
 import (
+	"context"
 	"fmt"
 	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
 
 	"github.com/tarantool/go-tarantool/v2"
 )
@@ -10,55 +12,139 @@ import (
 const (
 	createVoteFunc  = "create_vote"
 	deleteVoteIndex = "vote_user_poll_id"
+	getVoteIndex    = "vote_user_poll_id"
 	getVotesIndex   = "vote_poll_id"
+
+	createAnonymousVoteFunc = "create_anonymous_vote"
+	deleteAnonymousVoteFunc = "delete_anonymous_vote"
+	getBallotsIndex         = "ballot_poll_id"
 )
 
 // CreateVote adds new vote record to space "votes".
 // It uses lua-defined create_vote() func under the hood.
 //
 // In case user votes second time, his vote will simply be updated.
-func (r *Repo) CreateVote(vote entity.Vote) (*entity.Vote, error) {
+func (r *Repo) CreateVote(ctx context.Context, vote entity.Vote) (*entity.Vote, error) {
 	const op = "repo.tarantool.CreateVote"
 
 	data, err := r.conn.Do(
 		tarantool.NewCall17Request(createVoteFunc).
-			Args([]any{vote.User, vote.PollID, vote.OptionIDs}),
+			Args([]any{vote.User, vote.PollID, vote.OptionIDs, uintsToTuple(vote.Scores)}).
+			Context(ctx),
 	).Get()
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to create vote: %w", op, err)
 	}
 
-	tuple := data[0].([]any)
-	tupleIDs := tuple[3].([]any)
-	optionIDs := make([]uint64, len(tupleIDs))
-	for i := range optionIDs {
-		optionIDs[i] = uint64(tupleIDs[i].(int8))
-	}
-	newVote := entity.Vote{
-		VoteID:    uint64(tuple[0].(int8)),
-		User:      tuple[1].(string),
-		PollID:    uint64(tuple[2].(int8)),
-		OptionIDs: optionIDs,
-	}
+	newVotes := serializeVotes(data)
 
-	return &newVote, nil
+	return &newVotes[0], nil
 }
 
 // GetVotes returns all votes that belong to poll with pollID.
 // It is used to calculate poll results.
-func (r *Repo) GetVotes(pollID uint64) ([]entity.Vote, error) {
+func (r *Repo) GetVotes(ctx context.Context, pollID uint64) ([]entity.Vote, error) {
 	const op = "tarantool.repo.GetVotes"
 
 	data, err := r.conn.Do(
 		tarantool.NewSelectRequest(voteSpace).
 			Index(getVotesIndex).
-			Key([]any{int(pollID)}),
+			Key([]any{int(pollID)}).
+			Context(ctx),
 	).Get()
 	if err != nil {
 		return nil, fmt.Errorf("%s: failed to get votes: %w", op, err)
 	}
 
-	return serializeVotes(data), nil
+	votes := serializeVotes(data)
+
+	// Votes of anonymous poll are stored as ballots without voters.
+	data, err = r.conn.Do(
+		tarantool.NewSelectRequest(ballotSpace).
+			Index(getBallotsIndex).
+			Key([]any{int(pollID)}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get ballots: %w", op, err)
+	}
+
+	return append(votes, serializeBallots(data)...), nil
+}
+
+// CreateAnonymousVote saves vote in anonymous poll.
+// It uses lua-defined create_anonymous_vote() func under the hood.
+//
+// voterHash is saved to space "anonymous_voters" and prevents voting twice,
+// while option numbers and scores are saved to space "ballots" under ballotID.
+// Neither of them contains user ID, and they can't be linked with each other.
+//
+// In case voter has already voted, repo.ErrVoterExists is returned.
+func (r *Repo) CreateAnonymousVote(ctx context.Context, pollID uint64, voterHash, ballotID string, optionIDs, scores []uint64) (*entity.Vote, error) {
+	const op = "repo.tarantool.CreateAnonymousVote"
+
+	data, err := r.conn.Do(
+		tarantool.NewCall17Request(createAnonymousVoteFunc).
+			Args([]any{pollID, voterHash, ballotID, optionIDs, uintsToTuple(scores)}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to create anonymous vote: %w", op, err)
+	}
+
+	ballots := serializeBallots(data)
+	if len(ballots) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, repo.ErrVoterExists)
+	}
+
+	return &ballots[0], nil
+}
+
+// DeleteAnonymousVote removes voter and his ballot from anonymous poll.
+// It uses lua-defined delete_anonymous_vote() func under the hood.
+// Ballot of another poll isn't removed.
+//
+// (false, nil) indicates that user hasn't voted before.
+func (r *Repo) DeleteAnonymousVote(ctx context.Context, pollID uint64, voterHash, ballotID string) (isDeleted bool, err error) {
+	const op = "repo.tarantool.DeleteAnonymousVote"
+
+	data, err := r.conn.Do(
+		tarantool.NewCall17Request(deleteAnonymousVoteFunc).
+			Args([]any{pollID, voterHash, ballotID}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return false, fmt.Errorf("%s: failed to delete anonymous vote: %w", op, err)
+	}
+
+	if len(data) == 0 {
+		return false, nil
+	}
+	isDeleted, _ = data[0].(bool)
+
+	return isDeleted, nil
+}
+
+// GetVote returns vote of the user in poll with pollID.
+func (r *Repo) GetVote(ctx context.Context, user string, pollID uint64) (*entity.Vote, error) {
+	const op = "repo.tarantool.GetVote"
+
+	data, err := r.conn.Do(
+		tarantool.NewSelectRequest(voteSpace).
+			Index(getVoteIndex).
+			Key([]any{user, int(pollID)}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get vote: %w", op, err)
+	}
+
+	votes := serializeVotes(data)
+	if len(votes) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, repo.ErrVoteDoesNotExist)
+	}
+
+	return &votes[0], nil
 }
 
 // Converts tarantool response of type []any to []entity.Vote.
@@ -68,18 +154,30 @@ func serializeVotes(tuples []any) []entity.Vote {
 	for _, el := range tuples {
 		tuple := el.([]any)
 
-		// convert option nums to uint64 slice.
-		optsAny := tuple[3].([]any)
-		opts := make([]uint64, 0, len(optsAny))
-		for _, optAny := range optsAny {
-			opts = append(opts, uint64(optAny.(int8)))
-		}
-
 		votes = append(votes, entity.Vote{
-			VoteID:    uint64(tuple[0].(int8)),
+			VoteID:    toUint64(tuple[0]),
 			User:      tuple[1].(string),
-			PollID:    uint64(tuple[2].(int8)),
-			OptionIDs: opts,
+			PollID:    toUint64(tuple[2]),
+			OptionIDs: uintsFromTuple(tuple[3]),
+			Scores:    uintsFromTuple(field(tuple, 4)),
+		})
+	}
+
+	return votes
+}
+
+// Converts tarantool response with tuples of space "ballots" to []entity.Vote.
+// Ballots don't have numeric ID and user.
+func serializeBallots(tuples []any) []entity.Vote {
+	votes := make([]entity.Vote, 0, len(tuples))
+
+	for _, el := range tuples {
+		tuple := el.([]any)
+
+		votes = append(votes, entity.Vote{
+			PollID:    toUint64(tuple[1]),
+			OptionIDs: uintsFromTuple(tuple[2]),
+			Scores:    uintsFromTuple(field(tuple, 3)),
 		})
 	}
 
@@ -91,13 +189,14 @@ func serializeVotes(tuples []any) []entity.Vote {
 //
 // isDeleted indicates whether vote was deleted or not.
 // (false, nil) indicates that user hasn't voted before.
-func (r *Repo) DeleteVote(user string, pollID uint64) (isDeleted bool, err error) {
+func (r *Repo) DeleteVote(ctx context.Context, user string, pollID uint64) (isDeleted bool, err error) {
 	const op = "repo.tarantool.DeleteVote"
 
 	data, err := r.conn.Do(
 		tarantool.NewDeleteRequest(voteSpace).
 			Index(deleteVoteIndex).
-			Key([]any{user, int(pollID)}),
+			Key([]any{user, int(pollID)}).
+			Context(ctx),
 	).Get()
 	if err != nil {
 		return false, fmt.Errorf("%s: failed to delete vote: %w", op, err)
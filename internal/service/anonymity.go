@@ -0,0 +1,78 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"vote-bot/internal/entity"
+)
+
+// saltSize is a size of random per-poll salt in bytes.
+const saltSize = 16
+
+// ballotIDSize is a size of random ID of anonymous ballot in bytes.
+const ballotIDSize = 16
+
+// newSalt generates random salt for anonymous poll.
+func newSalt() (string, error) {
+	const op = "service.newSalt"
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("%s: failed to generate salt: %w", op, err)
+	}
+
+	return hex.EncodeToString(salt), nil
+}
+
+// newBallotID generates random ID of ballot in anonymous poll.
+// It isn't derived from the voter, so the ballot can't be found by voter even with the secret.
+func newBallotID() (string, error) {
+	const op = "service.newBallotID"
+
+	id := make([]byte, ballotIDSize)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("%s: failed to generate ballot id: %w", op, err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// voterHash marks that user has voted in anonymous poll.
+// It's keyed with the secret which isn't stored in repo, so the user can't be recovered from it.
+func voterHash(secret []byte, poll *entity.Poll, user string) string {
+	return keyedHash(secret, "voter", poll.Salt, user)
+}
+
+// newReceipt forms receipt of anonymous vote which is given only to the voter.
+// It's the only link between the voter and his ballot and it isn't stored anywhere,
+// so the voter needs it to retract the vote. Signature binds receipt to the voter,
+// so that nobody else could retract the ballot with it.
+func newReceipt(secret []byte, poll *entity.Poll, user string, ballotID string) string {
+	return ballotID + "." + keyedHash(secret, "receipt", poll.Salt, user+":"+ballotID)
+}
+
+// ballotFromReceipt returns ID of the ballot in case receipt was given to the user.
+func ballotFromReceipt(secret []byte, poll *entity.Poll, user string, receipt string) (string, error) {
+	ballotID, signature, ok := strings.Cut(strings.TrimSpace(receipt), ".")
+	if !ok {
+		return "", ErrInvalidReceipt
+	}
+
+	want := keyedHash(secret, "receipt", poll.Salt, user+":"+ballotID)
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return "", ErrInvalidReceipt
+	}
+
+	return ballotID, nil
+}
+
+func keyedHash(secret []byte, purpose, salt, user string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + ":" + salt + ":" + user))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
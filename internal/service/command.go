@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"vote-bot/internal/entity"
+)
+
+type CommandRepo interface {
+	ClaimCommand(ctx context.Context, cmd entity.ProcessedCommand) (*entity.ProcessedCommand, error)
+	SaveCommand(ctx context.Context, cmd entity.ProcessedCommand) error
+	DeleteCommandsBefore(ctx context.Context, before time.Time) (uint64, error)
+}
+
+// CommandService keeps outcomes of commands, so that the post delivered twice isn't handled twice.
+type CommandService struct {
+	commandRepo CommandRepo
+}
+
+func NewCommandService(commandRepo CommandRepo) *CommandService {
+	return &CommandService{commandRepo: commandRepo}
+}
+
+// Claim marks command of the post as processing.
+// In case the post was claimed before, claimed is false and the saved command is returned.
+func (s *CommandService) Claim(ctx context.Context, postID string, channel string) (cmd *entity.ProcessedCommand, claimed bool, err error) {
+	const op = "service.ClaimCommand"
+
+	existing, err := s.commandRepo.ClaimCommand(ctx, entity.ProcessedCommand{
+		PostID:      postID,
+		Channel:     channel,
+		ProcessedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: failed to claim command: %w", op, ctxError(ctx, err))
+	}
+
+	if existing != nil {
+		return existing, false, nil
+	}
+
+	return nil, true, nil
+}
+
+// Complete saves the response to the command, which is going to be sent.
+func (s *CommandService) Complete(ctx context.Context, cmd entity.ProcessedCommand) error {
+	const op = "service.CompleteCommand"
+
+	cmd.Status = entity.CommandStatusDone
+	if err := s.commandRepo.SaveCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("%s: failed to save command: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// MarkSent saves that response to the command was sent.
+func (s *CommandService) MarkSent(ctx context.Context, cmd entity.ProcessedCommand) error {
+	const op = "service.MarkCommandSent"
+
+	cmd.Status = entity.CommandStatusSent
+	if err := s.commandRepo.SaveCommand(ctx, cmd); err != nil {
+		return fmt.Errorf("%s: failed to save command: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes commands processed more than ttl ago and returns their number.
+func (s *CommandService) DeleteExpired(ctx context.Context, ttl time.Duration) (uint64, error) {
+	const op = "service.DeleteExpiredCommands"
+
+	deleted, err := s.commandRepo.DeleteCommandsBefore(ctx, time.Now().Add(-ttl))
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to delete commands: %w", op, ctxError(ctx, err))
+	}
+
+	return deleted, nil
+}
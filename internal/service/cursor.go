@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+)
+
+type CursorRepo interface {
+	GetCursor(ctx context.Context, channel string) (*entity.Cursor, error)
+	SaveCursor(ctx context.Context, cursor entity.Cursor) error
+}
+
+// CursorService keeps track of posts handled by the bot in channels.
+type CursorService struct {
+	cursorRepo CursorRepo
+}
+
+func NewCursorService(cursorRepo CursorRepo) *CursorService {
+	return &CursorService{cursorRepo: cursorRepo}
+}
+
+// GetCursor returns high-water mark of handled posts in the channel.
+// ErrCursorNotFound is returned in case no posts were handled there yet.
+func (s *CursorService) GetCursor(ctx context.Context, channel string) (*entity.Cursor, error) {
+	const op = "service.GetCursor"
+
+	cursor, err := s.cursorRepo.GetCursor(ctx, channel)
+	if err != nil {
+		if errors.Is(err, repo.ErrCursorDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrCursorNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: failed to get cursor: %w", op, ctxError(ctx, err))
+	}
+
+	return cursor, nil
+}
+
+// SaveCursor persists the cursor, so that bot catches up from it after restart.
+func (s *CursorService) SaveCursor(ctx context.Context, cursor entity.Cursor) error {
+	const op = "service.SaveCursor"
+
+	if err := s.cursorRepo.SaveCursor(ctx, cursor); err != nil {
+		return fmt.Errorf("%s: failed to save cursor: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
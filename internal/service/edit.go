@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+)
+
+type EditRepo interface {
+	GetPoll(ctx context.Context, pollID uint64) (*entity.Poll, error)
+	GetOptions(ctx context.Context, pollID uint64) ([]entity.Option, error)
+
+	// Every change is saved together with the edit describing it.
+	RenamePoll(ctx context.Context, edit entity.PollEdit) error
+	AddOption(ctx context.Context, edit entity.PollEdit) (*entity.Option, error)
+	RenameOption(ctx context.Context, edit entity.PollEdit) error
+	// RenumberOptions gives option with number i+1 number nums[i], options with zero number are removed.
+	// Options in votes are renumbered too, it returns votes which lost options.
+	RenumberOptions(ctx context.Context, edit entity.PollEdit, nums []uint64) ([]entity.Vote, error)
+	GetPollEdits(ctx context.Context, pollID uint64) ([]entity.PollEdit, error)
+	// ReopenPoll makes the finished poll open with deadline closesAt and records who reopened it.
+	// Post with results of the poll is forgotten.
+	ReopenPoll(ctx context.Context, edit entity.PollEdit, closesAt time.Time) error
+}
+
+// EditService changes polls after they were created. Only creator can edit the poll
+// and only until it's finished, but finished poll can be reopened. Edits are kept as history of the poll.
+type EditService struct {
+	editRepo EditRepo
+}
+
+func NewEditService(editRepo EditRepo) *EditService {
+	return &EditService{editRepo: editRepo}
+}
+
+// RenamePoll changes name of the poll.
+func (s *EditService) RenamePoll(ctx context.Context, pollID uint64, user string, channel string, name string) error {
+	const op = "service.RenamePoll"
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("%s: %w", op, ErrEmptyName)
+	}
+
+	poll, err := s.editablePoll(ctx, pollID, user, channel)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	edit := newEdit(poll, user, entity.EditRenamePoll)
+	edit.OldValue, edit.NewValue = poll.Name, name
+	if err := s.editRepo.RenamePoll(ctx, edit); err != nil {
+		return fmt.Errorf("%s: failed to rename poll: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// AddOption adds option to the end of the poll, it gets the next number.
+func (s *EditService) AddOption(ctx context.Context, pollID uint64, user string, channel string, name string) (*entity.Option, error) {
+	const op = "service.AddOption"
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrEmptyName)
+	}
+
+	poll, err := s.editablePoll(ctx, pollID, user, channel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	edit := newEdit(poll, user, entity.EditAddOption)
+	edit.NewValue = name
+	option, err := s.editRepo.AddOption(ctx, edit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to add option: %w", op, ctxError(ctx, err))
+	}
+
+	return option, nil
+}
+
+// RenameOption changes name of the option, votes for it are kept.
+func (s *EditService) RenameOption(ctx context.Context, pollID uint64, user string, channel string, num uint64, name string) error {
+	const op = "service.RenameOption"
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("%s: %w", op, ErrEmptyName)
+	}
+
+	poll, err := s.editablePoll(ctx, pollID, user, channel)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, err := s.editRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+	if num == 0 || num > uint64(len(options)) {
+		return fmt.Errorf("%s: %w", op, ErrOptionNotFound)
+	}
+
+	edit := newEdit(poll, user, entity.EditRenameOption)
+	edit.OptionNum = num
+	edit.OldValue, edit.NewValue = options[num-1].Name, name
+	if err := s.editRepo.RenameOption(ctx, edit); err != nil {
+		return fmt.Errorf("%s: failed to rename option: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// RemoveOption removes the option from the poll. Options after it move one number up.
+//
+// The option is stripped from votes, votes left without options are deleted.
+// Changed votes are returned, so that their voters could be notified.
+func (s *EditService) RemoveOption(ctx context.Context, pollID uint64, user string, channel string, num uint64) (*entity.OptionRemoval, error) {
+	const op = "service.RemoveOption"
+
+	poll, err := s.editablePoll(ctx, pollID, user, channel)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, err := s.editRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+	if num == 0 || num > uint64(len(options)) {
+		return nil, fmt.Errorf("%s: %w", op, ErrOptionNotFound)
+	}
+	if len(options) == 1 {
+		return nil, fmt.Errorf("%s: %w", op, ErrLastOption)
+	}
+
+	nums := make([]uint64, len(options))
+	for i := range nums {
+		switch old := uint64(i + 1); {
+		case old < num:
+			nums[i] = old
+		case old > num:
+			nums[i] = old - 1
+		}
+	}
+
+	edit := newEdit(poll, user, entity.EditRemoveOption)
+	edit.OptionNum = num
+	edit.OldValue = options[num-1].Name
+	votes, err := s.editRepo.RenumberOptions(ctx, edit, nums)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to remove option: %w", op, ctxError(ctx, err))
+	}
+
+	return &entity.OptionRemoval{Option: options[num-1], Votes: votes}, nil
+}
+
+// MoveOption moves the option to the position, options between them shift by one.
+// Votes keep the same choice, since options in them are renumbered as well.
+func (s *EditService) MoveOption(ctx context.Context, pollID uint64, user string, channel string, num uint64, position uint64) error {
+	const op = "service.MoveOption"
+
+	poll, err := s.editablePoll(ctx, pollID, user, channel)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, err := s.editRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+	if num == 0 || num > uint64(len(options)) {
+		return fmt.Errorf("%s: %w", op, ErrOptionNotFound)
+	}
+	if position == 0 || position > uint64(len(options)) {
+		return fmt.Errorf("%s: %w", op, ErrInvalidPosition)
+	}
+	// Option is already there, so there's nothing to save.
+	if num == position {
+		return nil
+	}
+
+	nums := make([]uint64, len(options))
+	for i := range nums {
+		switch old := uint64(i + 1); {
+		case old == num:
+			nums[i] = position
+		case num < position && old > num && old <= position:
+			nums[i] = old - 1
+		case position < num && old >= position && old < num:
+			nums[i] = old + 1
+		default:
+			nums[i] = old
+		}
+	}
+
+	edit := newEdit(poll, user, entity.EditMoveOption)
+	edit.OptionNum = num
+	edit.OldValue, edit.NewValue = strconv.FormatUint(num, 10), strconv.FormatUint(position, 10)
+	if _, err := s.editRepo.RenumberOptions(ctx, edit, nums); err != nil {
+		return fmt.Errorf("%s: failed to move option: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// GetPollEdits returns history of the poll from the oldest edit to the newest one.
+func (s *EditService) GetPollEdits(ctx context.Context, pollID uint64, channel string) ([]entity.PollEdit, error) {
+	const op = "service.GetPollEdits"
+
+	poll, err := s.editRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	edits, err := s.editRepo.GetPollEdits(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get poll edits: %w", op, ctxError(ctx, err))
+	}
+
+	return edits, nil
+}
+
+// ReopenPoll makes the finished poll open again. Poll is finished by deadline closesAt,
+// unless it's zero. In that case the previous deadline is kept if it hasn't passed yet.
+//
+// Results posted when poll was finished aren't final anymore, their post is returned to mark them as superseded.
+func (s *EditService) ReopenPoll(ctx context.Context, pollID uint64, user string, channel string, closesAt time.Time) (*entity.Reopening, error) {
+	const op = "service.ReopenPoll"
+
+	poll, err := s.editRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	if poll.Creator != user {
+		return nil, fmt.Errorf("%s: %w", op, ErrNotPollOwner)
+	}
+
+	if !poll.IsFinished {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFinished)
+	}
+
+	now := time.Now()
+	switch {
+	case !closesAt.IsZero() && !closesAt.After(now):
+		return nil, fmt.Errorf("%s: %w", op, ErrDeadlineInPast)
+	case closesAt.IsZero() && poll.ClosesAt.After(now):
+		closesAt = poll.ClosesAt
+	}
+	// Tarantool keeps deadline in unix seconds.
+	closesAt = closesAt.Truncate(time.Second)
+
+	edit := newEdit(poll, user, entity.EditReopenPoll)
+	if !poll.ClosesAt.IsZero() {
+		edit.OldValue = poll.ClosesAt.UTC().Format(time.RFC3339)
+	}
+	if !closesAt.IsZero() {
+		edit.NewValue = closesAt.UTC().Format(time.RFC3339)
+	}
+	if err := s.editRepo.ReopenPoll(ctx, edit, closesAt); err != nil {
+		return nil, fmt.Errorf("%s: failed to reopen poll: %w", op, ctxError(ctx, err))
+	}
+
+	reopening := &entity.Reopening{Poll: *poll, SupersededPostID: poll.ResultsPostID}
+	reopening.Poll.IsFinished = false
+	reopening.Poll.ClosesAt = closesAt
+	reopening.Poll.ResultsPostID = ""
+	reopening.Poll.ReopenedBy = user
+	reopening.Poll.ReopenedAt = edit.EditedAt.Truncate(time.Second)
+
+	return reopening, nil
+}
+
+// editablePoll returns the poll in case user can edit it.
+func (s *EditService) editablePoll(ctx context.Context, pollID uint64, user string, channel string) (*entity.Poll, error) {
+	poll, err := s.editRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, ErrPollNotFound
+		}
+		return nil, fmt.Errorf("failed to find poll: %w", ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return nil, ErrPollNotFound
+	}
+
+	if poll.Creator != user {
+		return nil, ErrNotPollOwner
+	}
+
+	if poll.IsFinished {
+		return nil, ErrPollFinished
+	}
+
+	return poll, nil
+}
+
+func newEdit(poll *entity.Poll, user string, action entity.EditAction) entity.PollEdit {
+	return entity.PollEdit{
+		PollID:   poll.ID,
+		Editor:   user,
+		EditedAt: time.Now(),
+		Action:   action,
+	}
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo/memory"
+)
+
+// votesByUser returns options chosen by each voter of the poll.
+func votesByUser(t *testing.T, r *memory.Repo, pollID uint64) map[string][]uint64 {
+	t.Helper()
+
+	votes, err := r.GetVotes(context.Background(), pollID)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+
+	byUser := make(map[string][]uint64, len(votes))
+	for _, vote := range votes {
+		byUser[vote.User] = vote.OptionIDs
+	}
+
+	return byUser
+}
+
+// optionNames returns names of options of the poll in order of their numbers.
+func optionNames(t *testing.T, r *memory.Repo, pollID uint64) []string {
+	t.Helper()
+
+	options, err := r.GetOptions(context.Background(), pollID)
+	if err != nil {
+		t.Fatalf("failed to get options: %v", err)
+	}
+
+	names := make([]string, 0, len(options))
+	for i, option := range options {
+		if option.Num != uint64(i+1) {
+			t.Fatalf("option %q has number %d, want %d", option.Name, option.Num, i+1)
+		}
+		names = append(names, option.Name)
+	}
+
+	return names
+}
+
+// newEditTest creates ranked poll with options a, b, c, d and votes of users x, y and z.
+func newEditTest(t *testing.T) (*Service, *memory.Repo, *entity.Poll) {
+	t.Helper()
+
+	r := memory.NewRepo()
+	s := NewService(r, "")
+	poll := createTestPoll(t, s, entity.PollTypeRanked, 4)
+
+	for user, opts := range map[string][]uint64{
+		"x": {2},
+		"y": {1, 3},
+		"z": {4, 2, 1},
+	} {
+		if _, err := s.VoteService.Vote(context.Background(), poll.ID, user, testChannel, opts, nil); err != nil {
+			t.Fatalf("failed to vote: %v", err)
+		}
+	}
+
+	return s, r, poll
+}
+
+func TestEditService_RemoveOption(t *testing.T) {
+	tests := []struct {
+		name        string
+		num         uint64
+		wantErr     error
+		wantOptions []string
+		wantVotes   map[string][]uint64
+		// wantChanged are voters whose votes lost the option.
+		wantChanged []string
+	}{
+		{
+			name:        "option in the middle",
+			num:         2,
+			wantOptions: []string{"a", "c", "d"},
+			wantVotes:   map[string][]uint64{"y": {1, 2}, "z": {3, 1}},
+			wantChanged: []string{"x", "z"},
+		},
+		{
+			name:        "the first option",
+			num:         1,
+			wantOptions: []string{"b", "c", "d"},
+			wantVotes:   map[string][]uint64{"x": {1}, "y": {2}, "z": {3, 1}},
+			wantChanged: []string{"y", "z"},
+		},
+		{
+			name:        "the last option",
+			num:         4,
+			wantOptions: []string{"a", "b", "c"},
+			wantVotes:   map[string][]uint64{"x": {2}, "y": {1, 3}, "z": {2, 1}},
+			wantChanged: []string{"z"},
+		},
+		{
+			name:    "unknown option",
+			num:     5,
+			wantErr: ErrOptionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, r, poll := newEditTest(t)
+
+			removal, err := s.EditService.RemoveOption(context.Background(), poll.ID, testCreator, testChannel, tt.num)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RemoveOption() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := optionNames(t, r, poll.ID); !slices.Equal(got, tt.wantOptions) {
+				t.Errorf("options = %v, want %v", got, tt.wantOptions)
+			}
+
+			got := votesByUser(t, r, poll.ID)
+			if len(got) != len(tt.wantVotes) {
+				t.Errorf("votes = %v, want %v", got, tt.wantVotes)
+			}
+			for user, want := range tt.wantVotes {
+				if !slices.Equal(got[user], want) {
+					t.Errorf("vote of %s = %v, want %v", user, got[user], want)
+				}
+			}
+
+			var changed []string
+			for _, vote := range removal.Votes {
+				changed = append(changed, vote.User)
+				if len(vote.OptionIDs) == 0 && got[vote.User] != nil {
+					t.Errorf("empty vote of %s wasn't deleted", vote.User)
+				}
+			}
+			slices.Sort(changed)
+			if !slices.Equal(changed, tt.wantChanged) {
+				t.Errorf("RemoveOption() changed votes of %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestEditService_RemoveLastOption(t *testing.T) {
+	s := NewService(memory.NewRepo(), "")
+	poll := createTestPoll(t, s, entity.PollTypeSingle, 1)
+
+	_, err := s.EditService.RemoveOption(context.Background(), poll.ID, testCreator, testChannel, 1)
+	if !errors.Is(err, ErrLastOption) {
+		t.Errorf("RemoveOption() error = %v, want %v", err, ErrLastOption)
+	}
+}
+
+func TestEditService_RemoveOnlyChosenAnonymousOption(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(memory.NewRepo(), "secret")
+	poll := createAnonymousTestPoll(t, s, entity.PollTypeSingle)
+
+	receipt, err := s.VoteService.Vote(ctx, poll.ID, "x", testChannel, []uint64{2}, nil)
+	if err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+	if _, err := s.VoteService.Vote(ctx, poll.ID, "y", testChannel, []uint64{1}, nil); err != nil {
+		t.Fatalf("failed to vote: %v", err)
+	}
+
+	if _, err := s.EditService.RemoveOption(ctx, poll.ID, testCreator, testChannel, 2); err != nil {
+		t.Fatalf("RemoveOption() error = %v", err)
+	}
+
+	// Emptied ballot of x isn't counted, though x can't be found by it.
+	info, err := s.VoteService.GetPollInfo(ctx, poll.ID, testChannel)
+	if err != nil {
+		t.Fatalf("GetPollInfo() error = %v", err)
+	}
+	if info.Voters != 1 {
+		t.Errorf("GetPollInfo() voters = %d, want 1", info.Voters)
+	}
+	page, err := s.PollService.ListChannelPolls(ctx, testChannel, entity.PollStatusAll, 0, 10)
+	if err != nil {
+		t.Fatalf("ListChannelPolls() error = %v", err)
+	}
+	if len(page.Polls) != 1 || page.Polls[0].Voters != 1 {
+		t.Errorf("ListChannelPolls() = %+v, want poll with 1 voter", page.Polls)
+	}
+
+	// Voter is kept, so x retracts the emptied vote by receipt to vote again.
+	if _, err := s.VoteService.Vote(ctx, poll.ID, "x", testChannel, []uint64{1}, nil); !errors.Is(err, ErrAlreadyVoted) {
+		t.Errorf("Vote() error = %v, want %v", err, ErrAlreadyVoted)
+	}
+	if err := s.VoteService.RetractVote(ctx, poll.ID, "x", testChannel, receipt); err != nil {
+		t.Fatalf("RetractVote() error = %v", err)
+	}
+	if _, err := s.VoteService.Vote(ctx, poll.ID, "x", testChannel, []uint64{1}, nil); err != nil {
+		t.Errorf("Vote() after retraction error = %v", err)
+	}
+}
+
+func TestEditService_MoveOption(t *testing.T) {
+	tests := []struct {
+		name        string
+		num         uint64
+		position    uint64
+		wantErr     error
+		wantOptions []string
+		wantVotes   map[string][]uint64
+		wantEdits   int
+	}{
+		{
+			name:        "forward",
+			num:         1,
+			position:    3,
+			wantOptions: []string{"b", "c", "a", "d"},
+			wantVotes:   map[string][]uint64{"x": {1}, "y": {3, 2}, "z": {4, 1, 3}},
+			wantEdits:   1,
+		},
+		{
+			name:        "backward",
+			num:         4,
+			position:    2,
+			wantOptions: []string{"a", "d", "b", "c"},
+			wantVotes:   map[string][]uint64{"x": {3}, "y": {1, 4}, "z": {2, 3, 1}},
+			wantEdits:   1,
+		},
+		{
+			name:        "to the same position",
+			num:         2,
+			position:    2,
+			wantOptions: []string{"a", "b", "c", "d"},
+			wantVotes:   map[string][]uint64{"x": {2}, "y": {1, 3}, "z": {4, 2, 1}},
+		},
+		{
+			name:     "unknown option",
+			num:      5,
+			position: 1,
+			wantErr:  ErrOptionNotFound,
+		},
+		{
+			name:     "position out of range",
+			num:      1,
+			position: 5,
+			wantErr:  ErrInvalidPosition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, r, poll := newEditTest(t)
+
+			err := s.EditService.MoveOption(ctx, poll.ID, testCreator, testChannel, tt.num, tt.position)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MoveOption() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := optionNames(t, r, poll.ID); !slices.Equal(got, tt.wantOptions) {
+				t.Errorf("options = %v, want %v", got, tt.wantOptions)
+			}
+
+			got := votesByUser(t, r, poll.ID)
+			for user, want := range tt.wantVotes {
+				if !slices.Equal(got[user], want) {
+					t.Errorf("vote of %s = %v, want %v", user, got[user], want)
+				}
+			}
+
+			edits, err := s.EditService.GetPollEdits(ctx, poll.ID, testChannel)
+			if err != nil {
+				t.Fatalf("GetPollEdits() error = %v", err)
+			}
+			if len(edits) != tt.wantEdits {
+				t.Errorf("GetPollEdits() = %+v, want %d edits", edits, tt.wantEdits)
+			}
+		})
+	}
+}
+
+func TestEditService_Names(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(ctx context.Context, s *EditService, pollID uint64) error
+		wantErr error
+	}{
+		{
+			name: "rename poll",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				return s.RenamePoll(ctx, pollID, testCreator, testChannel, " new ")
+			},
+		},
+		{
+			name: "rename poll to blank name",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				return s.RenamePoll(ctx, pollID, testCreator, testChannel, " \t")
+			},
+			wantErr: ErrEmptyName,
+		},
+		{
+			name: "add option",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				_, err := s.AddOption(ctx, pollID, testCreator, testChannel, "e")
+				return err
+			},
+		},
+		{
+			name: "add option with empty name",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				_, err := s.AddOption(ctx, pollID, testCreator, testChannel, "")
+				return err
+			},
+			wantErr: ErrEmptyName,
+		},
+		{
+			name: "rename option",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				return s.RenameOption(ctx, pollID, testCreator, testChannel, 1, "first")
+			},
+		},
+		{
+			name: "rename option to blank name",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				return s.RenameOption(ctx, pollID, testCreator, testChannel, 1, "  ")
+			},
+			wantErr: ErrEmptyName,
+		},
+		{
+			name: "rename poll by other user",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				return s.RenamePoll(ctx, pollID, "other", testChannel, "new")
+			},
+			wantErr: ErrNotPollOwner,
+		},
+		{
+			name: "add option in other channel",
+			edit: func(ctx context.Context, s *EditService, pollID uint64) error {
+				_, err := s.AddOption(ctx, pollID, testCreator, "other", "e")
+				return err
+			},
+			wantErr: ErrPollNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, _, poll := newEditTest(t)
+
+			err := tt.edit(ctx, s.EditService, poll.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("edit error = %v, want %v", err, tt.wantErr)
+			}
+
+			edits, err := s.EditService.GetPollEdits(ctx, poll.ID, testChannel)
+			if err != nil {
+				t.Fatalf("GetPollEdits() error = %v", err)
+			}
+			if saved := len(edits) != 0; saved != (tt.wantErr == nil) {
+				t.Errorf("edit is saved = %v, want %v", saved, tt.wantErr == nil)
+			}
+			for _, edit := range edits {
+				if edit.NewValue != "new" && edit.NewValue != "e" && edit.NewValue != "first" {
+					t.Errorf("edit new value = %q, want trimmed name", edit.NewValue)
+				}
+			}
+		})
+	}
+}
+
+func TestEditService_FinishedPoll(t *testing.T) {
+	ctx := context.Background()
+	s, _, poll := newEditTest(t)
+
+	if err := s.PollService.FinishPoll(ctx, poll.ID, testCreator, testChannel); err != nil {
+		t.Fatalf("failed to finish poll: %v", err)
+	}
+
+	if _, err := s.EditService.AddOption(ctx, poll.ID, testCreator, testChannel, "e"); !errors.Is(err, ErrPollFinished) {
+		t.Errorf("AddOption() error = %v, want %v", err, ErrPollFinished)
+	}
+}
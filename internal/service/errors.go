@@ -1,15 +1,54 @@
 package service
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrPollNotFound = errors.New("poll with this id not found")
-	ErrNotPollOwner = errors.New("user is not the owner of the poll")
-	ErrPollFinished = errors.New("poll was finished")
+	ErrRequestCanceled = errors.New("request was canceled or timed out")
+
+	ErrPollNotFound    = errors.New("poll with this id not found")
+	ErrNotPollOwner    = errors.New("user is not the owner of the poll")
+	ErrPollFinished    = errors.New("poll was finished")
+	ErrPollNotFinished = errors.New("poll isn't finished")
+
+	ErrDeadlineInPast    = errors.New("poll deadline is in the past")
+	ErrAnonymousDisabled = errors.New("anonymous polls are disabled")
+	ErrInvalidMaxChoices = errors.New("max number of choices exceeds number of options")
+	ErrAnonymousVoters   = errors.New("voters of anonymous poll can't be shown")
+
+	ErrEmptyName       = errors.New("name can't be empty")
+	ErrOptionNotFound  = errors.New("option with this number not found")
+	ErrLastOption      = errors.New("the only option of the poll can't be removed")
+	ErrInvalidPosition = errors.New("position is out of range of options")
+
+	ErrCursorNotFound = errors.New("no posts were handled in the channel yet")
 
 	ErrNoVoteToCancel = errors.New("no vote to cancel")
-	ErrNoVotesInPoll = errors.New("no votes in poll yet")
+	ErrNotVoted       = errors.New("user hasn't voted in the poll")
+	ErrNoVotesInPoll  = errors.New("no votes in poll yet")
+
+	ErrAlreadyVoted    = errors.New("vote in anonymous poll can't be changed, only retracted")
+	ErrInvalidReceipt  = errors.New("receipt of anonymous vote is missing or invalid")
+	ErrAnonymousChoice = errors.New("choice in anonymous poll isn't linked to voter")
 
 	ErrOnlyOneOptionAllowed = errors.New("only one option in the poll is allowed")
 	ErrInvalidOptionNumber  = errors.New("there is option with invalid number")
+	ErrDuplicateOption      = errors.New("option is chosen more than once")
+	ErrTooManyOptions       = errors.New("too many options are chosen")
+	ErrScoreRequired        = errors.New("every option must be given a score")
+	ErrScoreNotAllowed      = errors.New("options can be scored only in score polls")
+	ErrInvalidScore         = errors.New("score is out of range")
 )
+
+// ctxError marks error of the request which context was canceled or timed out
+// with ErrRequestCanceled, other errors are returned as is.
+func ctxError(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrRequestCanceled, err)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type LeaseRepo interface {
+	AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name string, holder string) error
+}
+
+// LeaseService grants named leases to one holder at a time, e.g. leadership among bot replicas.
+type LeaseService struct {
+	leaseRepo LeaseRepo
+}
+
+func NewLeaseService(leaseRepo LeaseRepo) *LeaseService {
+	return &LeaseService{leaseRepo: leaseRepo}
+}
+
+// Acquire takes or renews the lease for ttl. It reports false in case the lease is held by someone else.
+func (s *LeaseService) Acquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	const op = "service.AcquireLease"
+
+	acquired, err := s.leaseRepo.AcquireLease(ctx, name, holder, ttl)
+	if err != nil {
+		return false, fmt.Errorf("%s: failed to acquire lease: %w", op, ctxError(ctx, err))
+	}
+
+	return acquired, nil
+}
+
+// Release frees the lease held by the holder, so that others don't wait for it to expire.
+func (s *LeaseService) Release(ctx context.Context, name string, holder string) error {
+	const op = "service.ReleaseLease"
+
+	if err := s.leaseRepo.ReleaseLease(ctx, name, holder); err != nil {
+		return fmt.Errorf("%s: failed to release lease: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
@@ -1,48 +1,124 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 	"vote-bot/internal/entity"
 	"vote-bot/internal/repo"
 )
 
 type PollRepo interface {
-	CreatePollWithOptions(poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error)
-	GetPoll(pollID uint64) (*entity.Poll, error)
-	FinishPoll(pollID uint64) error
-	DeletePoll(pollID uint64) error
+	CreatePollWithOptions(ctx context.Context, poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error)
+	GetPoll(ctx context.Context, pollID uint64) (*entity.Poll, error)
+	GetOptions(ctx context.Context, pollID uint64) ([]entity.Option, error)
+	GetPendingPolls(ctx context.Context) ([]entity.Poll, error)
+	ListPolls(ctx context.Context, filter entity.PollFilter, beforeID uint64, limit int) ([]entity.PollSummary, error)
+	FinishPoll(ctx context.Context, pollID uint64) error
+	SetPollPost(ctx context.Context, pollID uint64, postID string) error
+	SetResultsPost(ctx context.Context, pollID uint64, postID string) error
+	DeletePoll(ctx context.Context, pollID uint64) error
 }
 
 type PollService struct {
-	pollRepo PollRepo
+	pollRepo   PollRepo
+	anonSecret []byte
 }
 
-func NewPollService(pollRepo PollRepo) *PollService {
-	return &PollService{pollRepo: pollRepo}
+func NewPollService(pollRepo PollRepo, anonSecret string) *PollService {
+	return &PollService{pollRepo: pollRepo, anonSecret: []byte(anonSecret)}
 }
 
-func (s *PollService) CreatePoll(poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error) {
+func (s *PollService) CreatePoll(ctx context.Context, poll entity.Poll, options []entity.Option) (*entity.Poll, []entity.Option, error) {
 	const op = "service.CreatePoll"
 
-	newPoll, newOptions, err := s.pollRepo.CreatePollWithOptions(poll, options)
+	if !poll.ClosesAt.IsZero() && !poll.ClosesAt.After(time.Now()) {
+		return nil, nil, fmt.Errorf("%s: %w", op, ErrDeadlineInPast)
+	}
+
+	if poll.Type == "" {
+		poll.Type = entity.PollTypeSingle
+	}
+
+	if poll.Type != entity.PollTypeApproval {
+		poll.MaxChoices = 0
+	}
+	if poll.MaxChoices > uint64(len(options)) {
+		return nil, nil, fmt.Errorf("%s: %w", op, ErrInvalidMaxChoices)
+	}
+
+	if poll.IsAnonymous && poll.ShowVoters {
+		return nil, nil, fmt.Errorf("%s: %w", op, ErrAnonymousVoters)
+	}
+
+	poll.Salt = ""
+	if poll.IsAnonymous {
+		if len(s.anonSecret) == 0 {
+			return nil, nil, fmt.Errorf("%s: %w", op, ErrAnonymousDisabled)
+		}
+
+		salt, err := newSalt()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		poll.Salt = salt
+	}
+
+	newPoll, newOptions, err := s.pollRepo.CreatePollWithOptions(ctx, poll, options)
 	if err != nil {
-		return nil, nil, fmt.Errorf("%s: failed to create poll: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: failed to create poll: %w", op, ctxError(ctx, err))
 	}
 
 	return newPoll, newOptions, err
 }
 
-func (s *PollService) FinishPoll(pollID uint64, user string, channel string) error {
+// GetPoll returns poll created in the channel.
+func (s *PollService) GetPoll(ctx context.Context, pollID uint64, channel string) (*entity.Poll, error) {
+	const op = "service.GetPoll"
+
+	poll, err := s.pollRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: failed to get poll: %w", op, ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	return poll, nil
+}
+
+// GetOptions returns options of the poll created in the channel.
+func (s *PollService) GetOptions(ctx context.Context, pollID uint64, channel string) ([]entity.Option, error) {
+	const op = "service.GetOptions"
+
+	if _, err := s.GetPoll(ctx, pollID, channel); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	options, err := s.pollRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get options: %w", op, ctxError(ctx, err))
+	}
+
+	return options, nil
+}
+
+func (s *PollService) FinishPoll(ctx context.Context, pollID uint64, user string, channel string) error {
 	const op = "service.FinishPoll"
 
-	poll, err := s.pollRepo.GetPoll(pollID)
+	poll, err := s.pollRepo.GetPoll(ctx, pollID)
 	if err != nil {
 		if errors.Is(err, repo.ErrPollDoesNotExist) {
 			return fmt.Errorf("%s: %w", op, ErrPollNotFound)
 		}
 
-		return fmt.Errorf("%s: failed to get poll: %w", op, err)
+		return fmt.Errorf("%s: failed to get poll: %w", op, ctxError(ctx, err))
 	}
 
 	if poll.Channel != channel {
@@ -53,19 +129,111 @@ func (s *PollService) FinishPoll(pollID uint64, user string, channel string) err
 		return fmt.Errorf("%s: %w", op, ErrNotPollOwner)
 	}
 
-	return s.pollRepo.FinishPoll(pollID)
+	if poll.IsFinished {
+		return fmt.Errorf("%s: %w", op, ErrPollFinished)
+	}
+
+	if err := s.pollRepo.FinishPoll(ctx, pollID); err != nil {
+		return fmt.Errorf("%s: failed to finish poll: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// GetPendingPolls returns polls which have a deadline and are not finished yet.
+func (s *PollService) GetPendingPolls(ctx context.Context) ([]entity.Poll, error) {
+	const op = "service.GetPendingPolls"
+
+	polls, err := s.pollRepo.GetPendingPolls(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get pending polls: %w", op, ctxError(ctx, err))
+	}
+
+	return polls, nil
 }
 
-func (s *PollService) DeletePoll(pollID uint64, user string, channel string) error {
+// ListChannelPolls returns page of polls created in the channel, from the newest to the oldest.
+// Polls with ID less than beforeID are returned, unless it's 0.
+func (s *PollService) ListChannelPolls(
+	ctx context.Context,
+	channel string,
+	status entity.PollStatus,
+	beforeID uint64,
+	limit int,
+) (*entity.PollPage, error) {
+	const op = "service.ListChannelPolls"
+
+	page, err := s.listPolls(ctx, entity.PollFilter{Channel: channel, Status: status}, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+// ListUserPolls returns page of polls created by the user in all channels, from the newest to the oldest.
+// Polls with ID less than beforeID are returned, unless it's 0.
+func (s *PollService) ListUserPolls(ctx context.Context, user string, beforeID uint64, limit int) (*entity.PollPage, error) {
+	const op = "service.ListUserPolls"
+
+	page, err := s.listPolls(ctx, entity.PollFilter{Creator: user}, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+func (s *PollService) listPolls(ctx context.Context, filter entity.PollFilter, beforeID uint64, limit int) (*entity.PollPage, error) {
+	// One more poll is requested to find out whether there is the next page.
+	polls, err := s.pollRepo.ListPolls(ctx, filter, beforeID, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list polls: %w", ctxError(ctx, err))
+	}
+
+	page := &entity.PollPage{Polls: polls}
+	if len(polls) > limit {
+		page.Polls = polls[:limit]
+		page.NextBefore = polls[limit-1].Poll.ID
+	}
+
+	return page, nil
+}
+
+// SetPollPost saves ID of the post which announces the poll,
+// so that the post could be updated when poll changes.
+func (s *PollService) SetPollPost(ctx context.Context, pollID uint64, postID string) error {
+	const op = "service.SetPollPost"
+
+	if err := s.pollRepo.SetPollPost(ctx, pollID, postID); err != nil {
+		return fmt.Errorf("%s: failed to set poll post: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// SetResultsPost saves ID of the post with results of the finished poll,
+// so that they could be marked as superseded when poll is reopened.
+func (s *PollService) SetResultsPost(ctx context.Context, pollID uint64, postID string) error {
+	const op = "service.SetResultsPost"
+
+	if err := s.pollRepo.SetResultsPost(ctx, pollID, postID); err != nil {
+		return fmt.Errorf("%s: failed to set results post: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+func (s *PollService) DeletePoll(ctx context.Context, pollID uint64, user string, channel string) error {
 	const op = "service.DeletePoll"
 
-	poll, err := s.pollRepo.GetPoll(pollID)
+	poll, err := s.pollRepo.GetPoll(ctx, pollID)
 	if err != nil {
 		if errors.Is(err, repo.ErrPollDoesNotExist) {
 			return fmt.Errorf("%s: %w", op, ErrPollNotFound)
 		}
 
-		return fmt.Errorf("%s: failed to get poll: %w", op, err)
+		return fmt.Errorf("%s: failed to get poll: %w", op, ctxError(ctx, err))
 	}
 
 	if poll.Channel != channel {
@@ -76,5 +244,9 @@ func (s *PollService) DeletePoll(pollID uint64, user string, channel string) err
 		return fmt.Errorf("%s: %w", op, ErrNotPollOwner)
 	}
 
-	return s.pollRepo.DeletePoll(pollID)
+	if err := s.pollRepo.DeletePoll(ctx, pollID); err != nil {
+		return fmt.Errorf("%s: failed to delete poll: %w", op, ctxError(ctx, err))
+	}
+
+	return nil
 }
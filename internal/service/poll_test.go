@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo/memory"
+)
+
+const (
+	testCreator = "creator"
+	testChannel = "channel"
+)
+
+// createTestPoll creates poll with the given number of options in testChannel on behalf of testCreator.
+func createTestPoll(t *testing.T, s *Service, pollType entity.PollType, optionsCount int) *entity.Poll {
+	t.Helper()
+
+	options := make([]entity.Option, optionsCount)
+	for i := range options {
+		options[i].Name = string(rune('a' + i))
+	}
+
+	poll, _, err := s.PollService.CreatePoll(context.Background(), entity.Poll{
+		Name:    "poll",
+		Creator: testCreator,
+		Channel: testChannel,
+		Type:    pollType,
+	}, options)
+	if err != nil {
+		t.Fatalf("failed to create poll: %v", err)
+	}
+
+	return poll
+}
+
+func TestPollService_CreatePoll(t *testing.T) {
+	tests := []struct {
+		name       string
+		poll       entity.Poll
+		anonSecret string
+		wantErr    error
+	}{
+		{
+			name: "without deadline",
+			poll: entity.Poll{Name: "poll"},
+		},
+		{
+			name: "deadline in the future",
+			poll: entity.Poll{Name: "poll", ClosesAt: time.Now().Add(time.Hour)},
+		},
+		{
+			name:    "deadline in the past",
+			poll:    entity.Poll{Name: "poll", ClosesAt: time.Now().Add(-time.Minute)},
+			wantErr: ErrDeadlineInPast,
+		},
+		{
+			name:    "anonymous without secret",
+			poll:    entity.Poll{Name: "poll", IsAnonymous: true},
+			wantErr: ErrAnonymousDisabled,
+		},
+		{
+			name:       "anonymous with secret",
+			poll:       entity.Poll{Name: "poll", IsAnonymous: true},
+			anonSecret: "secret",
+		},
+		{
+			name:       "anonymous with voters shown",
+			poll:       entity.Poll{Name: "poll", IsAnonymous: true, ShowVoters: true},
+			anonSecret: "secret",
+			wantErr:    ErrAnonymousVoters,
+		},
+		{
+			name:    "more choices than options",
+			poll:    entity.Poll{Name: "poll", Type: entity.PollTypeApproval, MaxChoices: 3},
+			wantErr: ErrInvalidMaxChoices,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewService(memory.NewRepo(), tt.anonSecret)
+
+			tt.poll.Creator, tt.poll.Channel = testCreator, testChannel
+			poll, options, err := s.PollService.CreatePoll(context.Background(), tt.poll, []entity.Option{{Name: "a"}, {Name: "b"}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreatePoll() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if poll.Type != entity.PollTypeSingle && tt.poll.Type == "" {
+				t.Errorf("CreatePoll() type = %q, want %q", poll.Type, entity.PollTypeSingle)
+			}
+			if poll.IsAnonymous != (poll.Salt != "") {
+				t.Errorf("CreatePoll() salt = %q for anonymous = %v", poll.Salt, poll.IsAnonymous)
+			}
+			for i, option := range options {
+				if option.Num != uint64(i+1) || option.PollID != poll.ID {
+					t.Errorf("CreatePoll() option %d = %+v", i, option)
+				}
+			}
+		})
+	}
+}
+
+func TestPollService_FinishPoll(t *testing.T) {
+	tests := []struct {
+		name     string
+		pollID   uint64
+		user     string
+		channel  string
+		finished bool
+		wantErr  error
+	}{
+		{name: "by creator", user: testCreator, channel: testChannel},
+		{name: "by other user", user: "other", channel: testChannel, wantErr: ErrNotPollOwner},
+		{name: "from other channel", user: testCreator, channel: "other", wantErr: ErrPollNotFound},
+		{name: "unknown poll", pollID: 100, user: testCreator, channel: testChannel, wantErr: ErrPollNotFound},
+		{name: "already finished", user: testCreator, channel: testChannel, finished: true, wantErr: ErrPollFinished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewService(memory.NewRepo(), "")
+			poll := createTestPoll(t, s, entity.PollTypeSingle, 2)
+
+			if tt.finished {
+				if err := s.PollService.FinishPoll(ctx, poll.ID, testCreator, testChannel); err != nil {
+					t.Fatalf("failed to finish poll: %v", err)
+				}
+			}
+
+			pollID := poll.ID
+			if tt.pollID != 0 {
+				pollID = tt.pollID
+			}
+
+			err := s.PollService.FinishPoll(ctx, pollID, tt.user, tt.channel)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FinishPoll() error = %v, want %v", err, tt.wantErr)
+			}
+
+			got, err := s.PollService.GetPoll(ctx, poll.ID, testChannel)
+			if err != nil {
+				t.Fatalf("failed to get poll: %v", err)
+			}
+			if want := tt.wantErr == nil || tt.finished; got.IsFinished != want {
+				t.Errorf("poll is finished = %v, want %v", got.IsFinished, want)
+			}
+		})
+	}
+}
+
+func TestPollService_DeletePoll(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		channel string
+		wantErr error
+	}{
+		{name: "by creator", user: testCreator, channel: testChannel},
+		{name: "by other user", user: "other", channel: testChannel, wantErr: ErrNotPollOwner},
+		{name: "from other channel", user: testCreator, channel: "other", wantErr: ErrPollNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewService(memory.NewRepo(), "")
+			poll := createTestPoll(t, s, entity.PollTypeSingle, 2)
+
+			err := s.PollService.DeletePoll(ctx, poll.ID, tt.user, tt.channel)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeletePoll() error = %v, want %v", err, tt.wantErr)
+			}
+
+			_, err = s.PollService.GetPoll(ctx, poll.ID, testChannel)
+			if deleted := errors.Is(err, ErrPollNotFound); deleted != (tt.wantErr == nil) {
+				t.Errorf("poll is deleted = %v, want %v", deleted, tt.wantErr == nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo"
+)
+
+// GetResults counts results of the poll.
+// Ranked polls are counted with instant-runoff voting,
+// score polls are won by options with the highest average score.
+func (s *VoteService) GetResults(ctx context.Context, pollID uint64, channel string) (*entity.Results, error) {
+	const op = "service.GetResults"
+
+	// Get poll from repo to perform checks.
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
+	}
+
+	// Check whether poll was created in the channel from which it is being requested.
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	// Get poll parameters that can be voted for.
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+
+	// Get votes in this poll. In case there are no votes return an error.
+	votes, err := s.voteRepo.GetVotes(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get votes: %w", op, ctxError(ctx, err))
+	}
+	if len(votes) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoVotesInPoll)
+	}
+
+	results := countResults(poll, definedOptions, votes)
+	if poll.ShowVoters && !poll.IsAnonymous {
+		addVoters(results, votes)
+	}
+
+	return results, nil
+}
+
+// GetVoters counts results of the poll with voters of each option.
+// Voters of anonymous polls aren't known, so they are refused.
+func (s *VoteService) GetVoters(ctx context.Context, pollID uint64, channel string) (*entity.Results, error) {
+	const op = "service.GetVoters"
+
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	if poll.IsAnonymous {
+		return nil, fmt.Errorf("%s: %w", op, ErrAnonymousVoters)
+	}
+
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+
+	votes, err := s.voteRepo.GetVotes(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get votes: %w", op, ctxError(ctx, err))
+	}
+	if len(votes) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoVotesInPoll)
+	}
+
+	results := countResults(poll, definedOptions, votes)
+	addVoters(results, votes)
+
+	return results, nil
+}
+
+// GetExport returns results of the poll together with votes of each voter.
+// Votes aren't returned for anonymous polls.
+func (s *VoteService) GetExport(ctx context.Context, pollID uint64, channel string) (*entity.PollExport, error) {
+	const op = "service.GetExport"
+
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+
+	votes, err := s.voteRepo.GetVotes(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get votes: %w", op, ctxError(ctx, err))
+	}
+	if len(votes) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoVotesInPoll)
+	}
+
+	export := &entity.PollExport{
+		Results: *countResults(poll, definedOptions, votes),
+	}
+	if !poll.IsAnonymous {
+		export.Votes = votes
+	}
+
+	return export, nil
+}
+
+// countResults counts results of the poll with its winners, votes must not be empty.
+func countResults(poll *entity.Poll, options []entity.Option, votes []entity.Vote) *entity.Results {
+	results := tally(poll, options, votes)
+
+	switch poll.Type {
+	case entity.PollTypeRanked:
+		results.Rounds, results.Winners = instantRunoff(len(options), votes)
+	case entity.PollTypeScore:
+		results.Winners = bestScored(results.Options)
+	default:
+		results.Winners = mostVoted(countVotes(poll.Type, len(options), votes))
+	}
+
+	return results
+}
+
+// tally counts votes and their share for each option of the poll.
+// In score polls average score and distribution of scores are counted as well.
+func tally(poll *entity.Poll, options []entity.Option, votes []entity.Vote) *entity.Results {
+	counts := countVotes(poll.Type, len(options), votes)
+
+	results := &entity.Results{
+		Poll:        *poll,
+		Options:     make([]entity.OptionResult, 0, len(options)),
+		TotalVoters: uint64(len(votes)),
+	}
+	for _, vote := range votes {
+		results.TotalSelections += uint64(len(vote.OptionIDs))
+	}
+
+	for i, option := range options {
+		opt := entity.OptionResult{
+			Option: option,
+			Votes:  counts[i],
+		}
+		if results.TotalVoters != 0 {
+			opt.Percent = float64(opt.Votes) * 100 / float64(results.TotalVoters)
+		}
+		results.Options = append(results.Options, opt)
+	}
+
+	if poll.Type == entity.PollTypeScore {
+		countScores(results.Options, votes)
+	}
+
+	return results
+}
+
+// addVoters fills voters of each option of the results.
+// Votes without user, i.e. ballots of anonymous polls, are skipped.
+func addVoters(results *entity.Results, votes []entity.Vote) {
+	for _, vote := range votes {
+		if vote.User == "" {
+			continue
+		}
+		for _, opt := range vote.OptionIDs {
+			if opt == 0 || opt > uint64(len(results.Options)) {
+				continue
+			}
+			results.Options[opt-1].Voters = append(results.Options[opt-1].Voters, vote.User)
+		}
+	}
+}
+
+// countScores fills distribution and average score of options.
+// options[i] must be option with number i+1.
+func countScores(options []entity.OptionResult, votes []entity.Vote) {
+	for i := range options {
+		options[i].Distribution = make([]uint64, entity.MaxScore+1)
+	}
+
+	for _, vote := range votes {
+		for i, opt := range vote.OptionIDs {
+			if i >= len(vote.Scores) || opt == 0 || opt > uint64(len(options)) {
+				continue
+			}
+			if score := vote.Scores[i]; score <= entity.MaxScore {
+				options[opt-1].Distribution[score]++
+			}
+		}
+	}
+
+	for i := range options {
+		var sum uint64
+		for score, count := range options[i].Distribution {
+			sum += uint64(score) * count
+		}
+		if options[i].Votes != 0 {
+			options[i].Average = float64(sum) / float64(options[i].Votes)
+		}
+	}
+}
+
+// bestScored returns numbers of options with the highest average score.
+// Options nobody rated can't win.
+func bestScored(options []entity.OptionResult) []uint64 {
+	var (
+		winners []uint64
+		best    float64
+	)
+	for _, option := range options {
+		if option.Votes == 0 {
+			continue
+		}
+
+		switch {
+		case winners == nil || option.Average > best:
+			best, winners = option.Average, []uint64{option.Option.Num}
+		case option.Average == best:
+			winners = append(winners, option.Option.Num)
+		}
+	}
+
+	return winners
+}
+
+// countVotes returns slice where index is option number - 1,
+// and value is how many people voted for this option.
+// Only first preferences are counted in ranked polls.
+func countVotes(pollType entity.PollType, optionsCount int, votes []entity.Vote) []uint64 {
+	counts := make([]uint64, optionsCount)
+	for _, vote := range votes {
+		for i, opt := range vote.OptionIDs {
+			if pollType == entity.PollTypeRanked && i > 0 {
+				break
+			}
+			if opt == 0 || opt > uint64(optionsCount) {
+				continue
+			}
+			counts[opt-1]++
+		}
+	}
+
+	return counts
+}
+
+// mostVoted returns numbers of options with the maximum number of votes.
+// Nobody wins in case there are no votes at all.
+func mostVoted(counts []uint64) []uint64 {
+	maxVotes := slices.Max(append([]uint64{0}, counts...))
+	if maxVotes == 0 {
+		return nil
+	}
+
+	var winners []uint64
+	for i, count := range counts {
+		if count == maxVotes {
+			winners = append(winners, uint64(i+1))
+		}
+	}
+
+	return winners
+}
+
+// instantRunoff counts ranked ballots in rounds.
+//
+// Each round every ballot is counted for its highest ranked option that is still running.
+// Option with more than half of counted ballots wins. Otherwise options with the fewest
+// ballots are eliminated together and the next round starts. In case all running options
+// have the same number of ballots, they win together.
+func instantRunoff(optionsCount int, ballots []entity.Vote) (rounds []entity.Round, winners []uint64) {
+	running := make([]bool, optionsCount)
+	for i := range running {
+		running[i] = true
+	}
+
+	for {
+		round := entity.Round{Votes: make([]uint64, optionsCount)}
+
+		var counted uint64
+		for _, ballot := range ballots {
+			opt, ok := topRunning(ballot.OptionIDs, running)
+			if !ok {
+				round.Exhausted++
+				continue
+			}
+			round.Votes[opt-1]++
+			counted++
+		}
+
+		// Find options with the most and the fewest ballots among running ones.
+		var (
+			most, fewest    []uint64
+			maxVotes        uint64
+			minVotes        = ^uint64(0)
+			runningOptCount int
+		)
+		for i, votes := range round.Votes {
+			if !running[i] {
+				continue
+			}
+			runningOptCount++
+			opt := uint64(i + 1)
+
+			switch {
+			case votes > maxVotes:
+				maxVotes, most = votes, []uint64{opt}
+			case votes == maxVotes:
+				most = append(most, opt)
+			}
+
+			switch {
+			case votes < minVotes:
+				minVotes, fewest = votes, []uint64{opt}
+			case votes == minVotes:
+				fewest = append(fewest, opt)
+			}
+		}
+
+		switch {
+		case runningOptCount == 0 || counted == 0:
+			// All ballots are exhausted, nobody wins.
+			return append(rounds, round), nil
+		case maxVotes*2 > counted:
+			return append(rounds, round), most
+		case len(fewest) == runningOptCount:
+			// Tie between all running options.
+			return append(rounds, round), most
+		}
+
+		round.Eliminated = fewest
+		for _, opt := range fewest {
+			running[opt-1] = false
+		}
+		rounds = append(rounds, round)
+	}
+}
+
+// topRunning returns the highest ranked option which is still running.
+func topRunning(ranking []uint64, running []bool) (uint64, bool) {
+	for _, opt := range ranking {
+		if opt == 0 || opt > uint64(len(running)) {
+			continue
+		}
+		if running[opt-1] {
+			return opt, true
+		}
+	}
+
+	return 0, false
+}
+
+func hasDuplicates(opts []uint64) bool {
+	seen := make(map[uint64]struct{}, len(opts))
+	for _, opt := range opts {
+		if _, ok := seen[opt]; ok {
+			return true
+		}
+		seen[opt] = struct{}{}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"vote-bot/internal/entity"
+)
+
+// ballots forms ranked ballots from rankings of option numbers.
+func ballots(rankings ...[]uint64) []entity.Vote {
+	votes := make([]entity.Vote, 0, len(rankings))
+	for _, ranking := range rankings {
+		votes = append(votes, entity.Vote{OptionIDs: ranking})
+	}
+
+	return votes
+}
+
+func TestInstantRunoff(t *testing.T) {
+	tests := []struct {
+		name         string
+		optionsCount int
+		ballots      []entity.Vote
+		wantRounds   []entity.Round
+		wantWinners  []uint64
+	}{
+		{
+			name:         "majority in the first round",
+			optionsCount: 3,
+			ballots:      ballots([]uint64{1, 2}, []uint64{1}, []uint64{2, 1}, []uint64{1, 3}),
+			wantRounds:   []entity.Round{{Votes: []uint64{3, 1, 0}}},
+			wantWinners:  []uint64{1},
+		},
+		{
+			name:         "several rounds",
+			optionsCount: 4,
+			ballots: ballots(
+				[]uint64{1, 2}, []uint64{1, 2}, []uint64{1, 2},
+				[]uint64{2, 3}, []uint64{2, 3},
+				[]uint64{3, 2}, []uint64{3, 2},
+				[]uint64{4, 3},
+			),
+			wantRounds: []entity.Round{
+				{Votes: []uint64{3, 2, 2, 1}, Eliminated: []uint64{4}},
+				{Votes: []uint64{3, 2, 3, 0}, Eliminated: []uint64{2}},
+				{Votes: []uint64{3, 0, 5, 0}},
+			},
+			wantWinners: []uint64{3},
+		},
+		{
+			name:         "options with the fewest ballots are eliminated together",
+			optionsCount: 4,
+			ballots: ballots(
+				[]uint64{1}, []uint64{1}, []uint64{1},
+				[]uint64{2, 1}, []uint64{3, 4},
+				[]uint64{4}, []uint64{4},
+			),
+			wantRounds: []entity.Round{
+				{Votes: []uint64{3, 1, 1, 2}, Eliminated: []uint64{2, 3}},
+				{Votes: []uint64{4, 0, 0, 3}},
+			},
+			wantWinners: []uint64{1},
+		},
+		{
+			name:         "tie of all running options",
+			optionsCount: 3,
+			ballots:      ballots([]uint64{1, 3}, []uint64{2, 3}, []uint64{3, 1}, []uint64{1}, []uint64{2}, []uint64{3}),
+			wantRounds:   []entity.Round{{Votes: []uint64{2, 2, 2}}},
+			wantWinners:  []uint64{1, 2, 3},
+		},
+		{
+			name:         "tie after elimination",
+			optionsCount: 3,
+			ballots:      ballots([]uint64{1}, []uint64{1}, []uint64{2}, []uint64{2}, []uint64{3, 1}),
+			wantRounds: []entity.Round{
+				{Votes: []uint64{2, 2, 1}, Eliminated: []uint64{3}},
+				{Votes: []uint64{3, 2, 0}},
+			},
+			wantWinners: []uint64{1},
+		},
+		{
+			name:         "exhausted ballots aren't counted",
+			optionsCount: 3,
+			ballots:      ballots([]uint64{1}, []uint64{1}, []uint64{2}, []uint64{2}, []uint64{3}),
+			wantRounds: []entity.Round{
+				{Votes: []uint64{2, 2, 1}, Eliminated: []uint64{3}},
+				{Votes: []uint64{2, 2, 0}, Exhausted: 1},
+			},
+			wantWinners: []uint64{1, 2},
+		},
+		{
+			name:         "all ballots exhausted",
+			optionsCount: 2,
+			ballots:      ballots([]uint64{}, []uint64{3}, []uint64{0}),
+			wantRounds:   []entity.Round{{Votes: []uint64{0, 0}, Exhausted: 3}},
+		},
+		{
+			name:         "options out of range are skipped",
+			optionsCount: 2,
+			ballots:      ballots([]uint64{5, 2}, []uint64{0, 2}, []uint64{1}),
+			wantRounds:   []entity.Round{{Votes: []uint64{1, 2}}},
+			wantWinners:  []uint64{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rounds, winners := instantRunoff(tt.optionsCount, tt.ballots)
+
+			if !slices.Equal(winners, tt.wantWinners) {
+				t.Errorf("instantRunoff() winners = %v, want %v", winners, tt.wantWinners)
+			}
+			if len(rounds) != len(tt.wantRounds) {
+				t.Fatalf("instantRunoff() rounds = %+v, want %+v", rounds, tt.wantRounds)
+			}
+			for i, round := range rounds {
+				want := tt.wantRounds[i]
+				if !slices.Equal(round.Votes, want.Votes) ||
+					!slices.Equal(round.Eliminated, want.Eliminated) ||
+					round.Exhausted != want.Exhausted {
+					t.Errorf("instantRunoff() round %d = %+v, want %+v", i+1, round, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCountScores(t *testing.T) {
+	options := []entity.OptionResult{
+		{Option: entity.Option{Num: 1}, Votes: 2},
+		{Option: entity.Option{Num: 2}, Votes: 1},
+		{Option: entity.Option{Num: 3}},
+	}
+	votes := []entity.Vote{
+		{OptionIDs: []uint64{1, 2}, Scores: []uint64{5, 0}},
+		{OptionIDs: []uint64{1}, Scores: []uint64{2}},
+		// Options out of range and options without scores are skipped.
+		{OptionIDs: []uint64{4, 3}, Scores: []uint64{1}},
+	}
+
+	countScores(options, votes)
+
+	wantDistributions := [][]uint64{
+		{0, 0, 1, 0, 0, 1},
+		{1, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0},
+	}
+	wantAverages := []float64{3.5, 0, 0}
+	for i, option := range options {
+		if !slices.Equal(option.Distribution, wantDistributions[i]) {
+			t.Errorf("countScores() distribution of option %d = %v, want %v", i+1, option.Distribution, wantDistributions[i])
+		}
+		if math.Abs(option.Average-wantAverages[i]) > 1e-9 {
+			t.Errorf("countScores() average of option %d = %v, want %v", i+1, option.Average, wantAverages[i])
+		}
+	}
+}
+
+func TestBestScored(t *testing.T) {
+	option := func(num uint64, votes uint64, average float64) entity.OptionResult {
+		return entity.OptionResult{Option: entity.Option{Num: num}, Votes: votes, Average: average}
+	}
+
+	tests := []struct {
+		name    string
+		options []entity.OptionResult
+		want    []uint64
+	}{
+		{
+			name:    "the highest average",
+			options: []entity.OptionResult{option(1, 2, 3.5), option(2, 3, 4), option(3, 1, 1)},
+			want:    []uint64{2},
+		},
+		{
+			name:    "tie",
+			options: []entity.OptionResult{option(1, 2, 4), option(2, 3, 2), option(3, 1, 4)},
+			want:    []uint64{1, 3},
+		},
+		{
+			name:    "options nobody rated can't win",
+			options: []entity.OptionResult{option(1, 0, 0), option(2, 1, 0)},
+			want:    []uint64{2},
+		},
+		{
+			name:    "no ratings",
+			options: []entity.OptionResult{option(1, 0, 0), option(2, 0, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bestScored(tt.options); !slices.Equal(got, tt.want) {
+				t.Errorf("bestScored() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,16 +3,31 @@ package service
 type Repo interface {
 	VoteRepo
 	PollRepo
+	EditRepo
+	CursorRepo
+	CommandRepo
+	LeaseRepo
 }
 
 type Service struct {
-	PollService *PollService
-	VoteService *VoteService
+	PollService    *PollService
+	VoteService    *VoteService
+	EditService    *EditService
+	CursorService  *CursorService
+	CommandService *CommandService
+	LeaseService   *LeaseService
 }
 
-func NewService(repo Repo) *Service {
+// NewService creates services on top of repo.
+// anonSecret is used to hash voters of anonymous polls,
+// such polls can't be created in case it's empty.
+func NewService(repo Repo, anonSecret string) *Service {
 	return &Service{
-		PollService: NewPollService(repo),
-		VoteService: NewVoteService(repo),
+		PollService:    NewPollService(repo, anonSecret),
+		VoteService:    NewVoteService(repo, anonSecret),
+		EditService:    NewEditService(repo),
+		CursorService:  NewCursorService(repo),
+		CommandService: NewCommandService(repo),
+		LeaseService:   NewLeaseService(repo),
 	}
 }
@@ -1,88 +1,137 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"vote-bot/internal/entity"
 	"vote-bot/internal/repo"
 )
 
 type VoteRepo interface {
 	// for checking
-	GetPoll(pollID uint64) (*entity.Poll, error)
-	GetOptions(pollID uint64) ([]entity.Option, error)
+	GetPoll(ctx context.Context, pollID uint64) (*entity.Poll, error)
+	GetOptions(ctx context.Context, pollID uint64) ([]entity.Option, error)
 
-	CreateVote(vote entity.Vote) (*entity.Vote, error)
-	DeleteVote(user string, pollID uint64) (bool, error)
-	GetVotes(pollID uint64) ([]entity.Vote, error)
+	CreateVote(ctx context.Context, vote entity.Vote) (*entity.Vote, error)
+	GetVote(ctx context.Context, user string, pollID uint64) (*entity.Vote, error)
+	DeleteVote(ctx context.Context, user string, pollID uint64) (bool, error)
+	GetVotes(ctx context.Context, pollID uint64) ([]entity.Vote, error)
+
+	// for anonymous polls
+	// CreateAnonymousVote fails with repo.ErrVoterExists in case voter has already voted.
+	CreateAnonymousVote(ctx context.Context, pollID uint64, voterHash, ballotID string, optionIDs, scores []uint64) (*entity.Vote, error)
+	DeleteAnonymousVote(ctx context.Context, pollID uint64, voterHash, ballotID string) (bool, error)
 }
 
 type VoteService struct {
-	voteRepo VoteRepo
+	voteRepo   VoteRepo
+	anonSecret []byte
 }
 
-func NewVoteService(voteRepo VoteRepo) *VoteService {
-	return &VoteService{voteRepo: voteRepo}
+func NewVoteService(voteRepo VoteRepo, anonSecret string) *VoteService {
+	return &VoteService{voteRepo: voteRepo, anonSecret: []byte(anonSecret)}
 }
 
-func (s *VoteService) Vote(pollID uint64, user string, channel string, opts []uint64) error {
+// Vote saves choice of the user replacing the previous one.
+// In score polls scores[i] is a score given to opts[i], in other polls scores must be empty.
+//
+// Vote in anonymous poll can't be replaced. Its receipt is returned,
+// which must be given only to the user, since it's needed to retract the vote.
+// receipt is empty for other polls.
+func (s *VoteService) Vote(ctx context.Context, pollID uint64, user string, channel string, opts []uint64, scores []uint64) (receipt string, err error) {
 	const op = "service.Vote"
 
-	poll, err := s.voteRepo.GetPoll(pollID)
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
 	if err != nil {
 		if errors.Is(err, repo.ErrPollDoesNotExist) {
-			return fmt.Errorf("%s: %w", op, ErrPollNotFound)
+			return "", fmt.Errorf("%s: %w", op, ErrPollNotFound)
 		}
-		return fmt.Errorf("%s: failed to find poll: %w", op, err)
+		return "", fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
 	}
 
 	if poll.Channel != channel {
-		return fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		return "", fmt.Errorf("%s: %w", op, ErrPollNotFound)
 	}
 
 	if poll.IsFinished {
-		return fmt.Errorf("%s: %w", op, ErrPollFinished)
+		return "", fmt.Errorf("%s: %w", op, ErrPollFinished)
 	}
 
-	var isManyOpts bool
-	if len(opts) > 1 {
-		isManyOpts = true
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
 	}
-	if isManyOpts && !poll.IsMultiVote {
-		return fmt.Errorf("%s: %w", op, ErrOnlyOneOptionAllowed)
+
+	if err := checkChoice(poll, len(definedOptions), opts, scores); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	definedOptions, err := s.voteRepo.GetOptions(pollID)
-	if err != nil {
-		return fmt.Errorf("%s: failed to get options defined in the poll: %w", op, err)
+	if poll.IsAnonymous {
+		receipt, err := s.saveAnonymousVote(ctx, poll, user, opts, scores)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		return receipt, nil
 	}
-	for _, opt := range opts {
-		if opt > uint64(len(definedOptions)) {
-			return fmt.Errorf("%s: %w", op, ErrInvalidOptionNumber)
+
+	if err := s.saveVote(ctx, poll, user, opts, scores); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return "", nil
+}
+
+// checkChoice validates options and scores chosen in the poll.
+func checkChoice(poll *entity.Poll, optionsCount int, opts []uint64, scores []uint64) error {
+	if poll.Type == entity.PollTypeScore {
+		if len(scores) != len(opts) {
+			return ErrScoreRequired
+		}
+		for _, score := range scores {
+			if score > entity.MaxScore {
+				return ErrInvalidScore
+			}
 		}
+	} else if len(scores) != 0 {
+		return ErrScoreNotAllowed
 	}
 
-	_, err = s.voteRepo.CreateVote(entity.Vote{
-		PollID:    pollID,
-		OptionIDs: opts,
-		User:      user,
-	})
-	if err != nil {
-		return fmt.Errorf("%s: failed to create vote: %w", op, err)
+	if len(opts) > 1 && !poll.Type.AllowsManyOptions() {
+		return ErrOnlyOneOptionAllowed
+	}
+
+	if poll.Type == entity.PollTypeApproval && poll.MaxChoices != 0 && uint64(len(opts)) > poll.MaxChoices {
+		return ErrTooManyOptions
+	}
+
+	if hasDuplicates(opts) {
+		return ErrDuplicateOption
+	}
+
+	for _, opt := range opts {
+		if opt == 0 || opt > uint64(optionsCount) {
+			return ErrInvalidOptionNumber
+		}
 	}
 
 	return nil
 }
 
-func (s *VoteService) RetractVote(pollID uint64, user string, channel string) error {
+// RetractVote removes choice of the user in the poll.
+// Vote in anonymous poll is retracted only with receipt given when the user voted,
+// receipt is ignored in other polls.
+func (s *VoteService) RetractVote(ctx context.Context, pollID uint64, user string, channel string, receipt string) error {
 	const op = "service.RetractVote"
 
-	poll, err := s.voteRepo.GetPoll(pollID)
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
 	if err != nil {
 		if errors.Is(err, repo.ErrPollDoesNotExist) {
 			return fmt.Errorf("%s: %w", op, ErrPollNotFound)
 		}
-		return fmt.Errorf("%s: failed to find poll: %w", op, err)
+		return fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
 	}
 
 	if poll.Channel != channel {
@@ -93,9 +142,14 @@ func (s *VoteService) RetractVote(pollID uint64, user string, channel string) er
 		return fmt.Errorf("%s: %w", op, ErrPollFinished)
 	}
 
-	isDeleted, err := s.voteRepo.DeleteVote(user, pollID)
+	var isDeleted bool
+	if poll.IsAnonymous {
+		isDeleted, err = s.deleteAnonymousVote(ctx, poll, user, receipt)
+	} else {
+		isDeleted, err = s.deleteVote(ctx, poll, user)
+	}
 	if err != nil {
-		return fmt.Errorf("%s: failed to delete vote: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 	if !isDeleted {
 		return fmt.Errorf("%s: %w", op, ErrNoVoteToCancel)
@@ -104,56 +158,336 @@ func (s *VoteService) RetractVote(pollID uint64, user string, channel string) er
 	return nil
 }
 
-// GetResults returns poll resuts in the following format:
-// map["1) optionName"] = optionCount.
-func (s *VoteService) GetResults(pollID uint64, channel string) (map[string]uint64, error) {
-	const op = "servoce.GetResults"
+// ToggleOption adds option to the user's vote or removes it in case it was already chosen.
+// In polls with single choice the option replaces the previous one.
+// Options of score polls can't be toggled, they are rated with RateOption.
+// Options of anonymous polls can't be toggled either, since choice of the user isn't known.
+//
+// isChosen reports whether option is chosen after the toggle.
+func (s *VoteService) ToggleOption(ctx context.Context, pollID uint64, user string, channel string, opt uint64) (isChosen bool, err error) {
+	const op = "service.ToggleOption"
 
-	// Get poll from repo to perform checks.
-	poll, err := s.voteRepo.GetPoll(pollID)
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return false, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return false, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return false, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	if poll.IsFinished {
+		return false, fmt.Errorf("%s: %w", op, ErrPollFinished)
+	}
+
+	if poll.IsAnonymous {
+		return false, fmt.Errorf("%s: %w", op, ErrAnonymousChoice)
+	}
+
+	if poll.Type == entity.PollTypeScore {
+		return false, fmt.Errorf("%s: %w", op, ErrScoreRequired)
+	}
+
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return false, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+	if opt == 0 || opt > uint64(len(definedOptions)) {
+		return false, fmt.Errorf("%s: %w", op, ErrInvalidOptionNumber)
+	}
+
+	// Previous choice of the user. It stays empty in case user hasn't voted yet.
+	var chosen []uint64
+	vote, err := s.getVote(ctx, poll, user)
+	if err != nil && !errors.Is(err, repo.ErrVoteDoesNotExist) {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if vote != nil {
+		chosen = vote.OptionIDs
+	}
+
+	idx := slices.Index(chosen, opt)
+	switch {
+	case idx != -1:
+		chosen = slices.Delete(chosen, idx, idx+1)
+	case poll.Type.AllowsManyOptions():
+		// In ranked polls options are ranked in order of clicks.
+		chosen = append(chosen, opt)
+	default:
+		chosen = []uint64{opt}
+	}
+
+	if err := checkChoice(poll, len(definedOptions), chosen, nil); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Nothing left to vote for, so vote is retracted.
+	if len(chosen) == 0 {
+		if _, err := s.deleteVote(ctx, poll, user); err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		return false, nil
+	}
+
+	if err := s.saveVote(ctx, poll, user, chosen, nil); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return idx == -1, nil
+}
+
+// RateOption sets score of the option in the user's vote in score poll.
+// Scores of other options in the vote are kept, so it's refused in anonymous polls.
+func (s *VoteService) RateOption(ctx context.Context, pollID uint64, user string, channel string, opt uint64, score uint64) error {
+	const op = "service.RateOption"
+
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
+	}
+
+	if poll.Channel != channel {
+		return fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	if poll.IsFinished {
+		return fmt.Errorf("%s: %w", op, ErrPollFinished)
+	}
+
+	if poll.IsAnonymous {
+		return fmt.Errorf("%s: %w", op, ErrAnonymousChoice)
+	}
+
+	if poll.Type != entity.PollTypeScore {
+		return fmt.Errorf("%s: %w", op, ErrScoreNotAllowed)
+	}
+
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+
+	var opts, scores []uint64
+	vote, err := s.getVote(ctx, poll, user)
+	if err != nil && !errors.Is(err, repo.ErrVoteDoesNotExist) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if vote != nil {
+		opts, scores = vote.OptionIDs, vote.Scores
+	}
+
+	if idx := slices.Index(opts, opt); idx != -1 && idx < len(scores) {
+		scores[idx] = score
+	} else {
+		opts, scores = append(opts, opt), append(scores, score)
+	}
+
+	if err := checkChoice(poll, len(definedOptions), opts, scores); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.saveVote(ctx, poll, user, opts, scores); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// saveVote saves choice of the user in poll which isn't anonymous.
+func (s *VoteService) saveVote(ctx context.Context, poll *entity.Poll, user string, opts []uint64, scores []uint64) error {
+	_, err := s.voteRepo.CreateVote(ctx, entity.Vote{
+		PollID:    poll.ID,
+		OptionIDs: opts,
+		User:      user,
+		Scores:    scores,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create vote: %w", ctxError(ctx, err))
+	}
+
+	return nil
+}
+
+// getVote returns current choice of the user in poll which isn't anonymous.
+func (s *VoteService) getVote(ctx context.Context, poll *entity.Poll, user string) (*entity.Vote, error) {
+	vote, err := s.voteRepo.GetVote(ctx, user, poll.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vote: %w", ctxError(ctx, err))
+	}
+
+	return vote, nil
+}
+
+// deleteVote removes choice of the user in poll which isn't anonymous.
+// false is returned in case user hasn't voted before.
+func (s *VoteService) deleteVote(ctx context.Context, poll *entity.Poll, user string) (bool, error) {
+	isDeleted, err := s.voteRepo.DeleteVote(ctx, user, poll.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete vote: %w", ctxError(ctx, err))
+	}
+
+	return isDeleted, nil
+}
+
+// saveAnonymousVote saves voter apart from his choice and returns receipt of the vote.
+// User ID isn't stored, and the ballot gets random ID, so it can't be linked to voter.
+func (s *VoteService) saveAnonymousVote(ctx context.Context, poll *entity.Poll, user string, opts []uint64, scores []uint64) (string, error) {
+	ballotID, err := newBallotID()
+	if err != nil {
+		return "", err
+	}
+
+	voter := voterHash(s.anonSecret, poll, user)
+	if _, err := s.voteRepo.CreateAnonymousVote(ctx, poll.ID, voter, ballotID, opts, scores); err != nil {
+		if errors.Is(err, repo.ErrVoterExists) {
+			return "", ErrAlreadyVoted
+		}
+		return "", fmt.Errorf("failed to create anonymous vote: %w", ctxError(ctx, err))
+	}
+
+	return newReceipt(s.anonSecret, poll, user, ballotID), nil
+}
+
+// deleteAnonymousVote removes voter and the ballot from the receipt.
+// false is returned in case user hasn't voted before.
+func (s *VoteService) deleteAnonymousVote(ctx context.Context, poll *entity.Poll, user string, receipt string) (bool, error) {
+	ballotID, err := ballotFromReceipt(s.anonSecret, poll, user, receipt)
+	if err != nil {
+		return false, err
+	}
+
+	voter := voterHash(s.anonSecret, poll, user)
+	isDeleted, err := s.voteRepo.DeleteAnonymousVote(ctx, poll.ID, voter, ballotID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete anonymous vote: %w", ctxError(ctx, err))
+	}
+
+	return isDeleted, nil
+}
+
+// GetTally returns current results of the poll.
+//
+// Unlike GetResults it doesn't fail when there are no votes yet
+// and doesn't count rounds of ranked polls.
+func (s *VoteService) GetTally(ctx context.Context, pollID uint64, channel string) (*entity.Results, error) {
+	const op = "service.GetTally"
+
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
 	if err != nil {
 		if errors.Is(err, repo.ErrPollDoesNotExist) {
 			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
 		}
-		return nil, fmt.Errorf("%s: failed to find poll: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
 	}
 
-	// Check whether poll was created in the channel from which it is being requested.
 	if poll.Channel != channel {
 		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
 	}
 
-	// Get poll parameters that can be voted for.
-	definedOptions, err := s.voteRepo.GetOptions(pollID)
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+
+	votes, err := s.voteRepo.GetVotes(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get votes: %w", op, ctxError(ctx, err))
+	}
+
+	return tally(poll, definedOptions, votes), nil
+}
+
+// GetPollInfo returns definition of the poll and number of people who voted in it.
+func (s *VoteService) GetPollInfo(ctx context.Context, pollID uint64, channel string) (*entity.PollInfo, error) {
+	const op = "service.GetPollInfo"
+
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, err)
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+		}
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
 	}
 
-	// Create slice for saving statistics where
-	// index is option number, and value is how many peopel voted for this option.
-	results := make([]uint64, len(definedOptions))
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
 
-	// Get votes in this poll. In case there are no votes return an error.
-	votes, err := s.voteRepo.GetVotes(pollID)
+	options, err := s.voteRepo.GetOptions(ctx, pollID)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to get votes: %w", op, err)
+		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
 	}
-	if len(votes) == 0 {
-		return nil, fmt.Errorf("%s: %w", op, ErrNoVotesInPoll)
+
+	votes, err := s.voteRepo.GetVotes(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get votes: %w", op, ctxError(ctx, err))
 	}
 
-	// Iterate over votes in order to form results 
-	for _, vote := range votes {
-		for _, opt := range vote.OptionIDs {
-			results[opt-1]++
+	return &entity.PollInfo{
+		Poll:    *poll,
+		Options: options,
+		Voters:  uint64(len(votes)),
+	}, nil
+}
+
+// GetUserVote returns current choice of the user in the poll.
+// It's refused in anonymous polls, since choice isn't linked to voter there.
+func (s *VoteService) GetUserVote(ctx context.Context, pollID uint64, user string, channel string) (*entity.UserVote, error) {
+	const op = "service.GetUserVote"
+
+	poll, err := s.voteRepo.GetPoll(ctx, pollID)
+	if err != nil {
+		if errors.Is(err, repo.ErrPollDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
 		}
+		return nil, fmt.Errorf("%s: failed to find poll: %w", op, ctxError(ctx, err))
 	}
 
-	// Format the results to make them human-readable.
-	fmtResults := make(map[string]uint64, len(results))
-	for option, count := range results {
-		fmtResults[fmt.Sprintf("%d) %s", option+1, definedOptions[option].Name)] = count
+	if poll.Channel != channel {
+		return nil, fmt.Errorf("%s: %w", op, ErrPollNotFound)
+	}
+
+	if poll.IsAnonymous {
+		return nil, fmt.Errorf("%s: %w", op, ErrAnonymousChoice)
+	}
+
+	vote, err := s.getVote(ctx, poll, user)
+	if err != nil {
+		if errors.Is(err, repo.ErrVoteDoesNotExist) {
+			return nil, fmt.Errorf("%s: %w", op, ErrNotVoted)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	definedOptions, err := s.voteRepo.GetOptions(ctx, pollID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get options defined in the poll: %w", op, ctxError(ctx, err))
+	}
+
+	optionsByNum := make(map[uint64]entity.Option, len(definedOptions))
+	for _, option := range definedOptions {
+		optionsByNum[option.Num] = option
+	}
+
+	userVote := &entity.UserVote{
+		Poll:    *poll,
+		Options: make([]entity.Option, 0, len(vote.OptionIDs)),
+		Scores:  vote.Scores,
+	}
+	for _, num := range vote.OptionIDs {
+		option, ok := optionsByNum[num]
+		if !ok {
+			return nil, fmt.Errorf("%s: vote contains unknown option %d", op, num)
+		}
+		userVote.Options = append(userVote.Options, option)
 	}
 
-	return fmtResults, nil
+	return userVote, nil
 }
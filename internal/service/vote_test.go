@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"vote-bot/internal/entity"
+	"vote-bot/internal/repo/memory"
+)
+
+func TestVoteService_Vote(t *testing.T) {
+	tests := []struct {
+		name     string
+		pollType entity.PollType
+		channel  string
+		finished bool
+		opts     []uint64
+		scores   []uint64
+		wantErr  error
+	}{
+		{name: "single option", pollType: entity.PollTypeSingle, opts: []uint64{2}},
+		{name: "several options in single poll", pollType: entity.PollTypeSingle, opts: []uint64{1, 2}, wantErr: ErrOnlyOneOptionAllowed},
+		{name: "several options in multi poll", pollType: entity.PollTypeMulti, opts: []uint64{1, 3}},
+		{name: "duplicate option", pollType: entity.PollTypeMulti, opts: []uint64{1, 1}, wantErr: ErrDuplicateOption},
+		{name: "option out of range", pollType: entity.PollTypeSingle, opts: []uint64{4}, wantErr: ErrInvalidOptionNumber},
+		{name: "zero option", pollType: entity.PollTypeSingle, opts: []uint64{0}, wantErr: ErrInvalidOptionNumber},
+		{name: "scores", pollType: entity.PollTypeScore, opts: []uint64{1, 2}, scores: []uint64{5, 0}},
+		{name: "missing scores", pollType: entity.PollTypeScore, opts: []uint64{1}, wantErr: ErrScoreRequired},
+		{name: "score out of range", pollType: entity.PollTypeScore, opts: []uint64{1}, scores: []uint64{6}, wantErr: ErrInvalidScore},
+		{name: "scores in single poll", pollType: entity.PollTypeSingle, opts: []uint64{1}, scores: []uint64{3}, wantErr: ErrScoreNotAllowed},
+		{name: "other channel", pollType: entity.PollTypeSingle, channel: "other", opts: []uint64{1}, wantErr: ErrPollNotFound},
+		{name: "finished poll", pollType: entity.PollTypeSingle, finished: true, opts: []uint64{1}, wantErr: ErrPollFinished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewService(memory.NewRepo(), "")
+			poll := createTestPoll(t, s, tt.pollType, 3)
+
+			if tt.finished {
+				if err := s.PollService.FinishPoll(ctx, poll.ID, testCreator, testChannel); err != nil {
+					t.Fatalf("failed to finish poll: %v", err)
+				}
+			}
+
+			channel := testChannel
+			if tt.channel != "" {
+				channel = tt.channel
+			}
+
+			_, err := s.VoteService.Vote(ctx, poll.ID, "voter", channel, tt.opts, tt.scores)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Vote() error = %v, want %v", err, tt.wantErr)
+			}
+
+			vote, err := s.VoteService.GetUserVote(ctx, poll.ID, "voter", testChannel)
+			if tt.wantErr != nil {
+				if !errors.Is(err, ErrNotVoted) {
+					t.Errorf("GetUserVote() error = %v, want %v", err, ErrNotVoted)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetUserVote() error = %v", err)
+			}
+
+			var nums []uint64
+			for _, opt := range vote.Options {
+				nums = append(nums, opt.Num)
+			}
+			if !slices.Equal(nums, tt.opts) || !slices.Equal(vote.Scores, tt.scores) {
+				t.Errorf("GetUserVote() = %v %v, want %v %v", nums, vote.Scores, tt.opts, tt.scores)
+			}
+		})
+	}
+}
+
+func TestVoteService_VoteAgainReplacesVote(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(memory.NewRepo(), "")
+	poll := createTestPoll(t, s, entity.PollTypeSingle, 2)
+
+	for _, opt := range []uint64{1, 2} {
+		if _, err := s.VoteService.Vote(ctx, poll.ID, "voter", testChannel, []uint64{opt}, nil); err != nil {
+			t.Fatalf("Vote() error = %v", err)
+		}
+	}
+
+	results, err := s.VoteService.GetResults(ctx, poll.ID, testChannel)
+	if err != nil {
+		t.Fatalf("GetResults() error = %v", err)
+	}
+	if results.TotalVoters != 1 || results.Options[0].Votes != 0 || results.Options[1].Votes != 1 {
+		t.Errorf("GetResults() = %+v, want the only vote for option 2", results.Options)
+	}
+	if !slices.Equal(results.Winners, []uint64{2}) {
+		t.Errorf("GetResults() winners = %v, want [2]", results.Winners)
+	}
+}
+
+func TestVoteService_RetractVote(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(memory.NewRepo(), "")
+	poll := createTestPoll(t, s, entity.PollTypeSingle, 2)
+
+	if err := s.VoteService.RetractVote(ctx, poll.ID, "voter", testChannel, ""); !errors.Is(err, ErrNoVoteToCancel) {
+		t.Fatalf("RetractVote() without vote error = %v, want %v", err, ErrNoVoteToCancel)
+	}
+
+	if _, err := s.VoteService.Vote(ctx, poll.ID, "voter", testChannel, []uint64{1}, nil); err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	if err := s.VoteService.RetractVote(ctx, poll.ID, "voter", "other", ""); !errors.Is(err, ErrPollNotFound) {
+		t.Fatalf("RetractVote() from other channel error = %v, want %v", err, ErrPollNotFound)
+	}
+	if err := s.VoteService.RetractVote(ctx, poll.ID, "voter", testChannel, ""); err != nil {
+		t.Fatalf("RetractVote() error = %v", err)
+	}
+
+	if _, err := s.VoteService.GetResults(ctx, poll.ID, testChannel); !errors.Is(err, ErrNoVotesInPoll) {
+		t.Errorf("GetResults() error = %v, want %v", err, ErrNoVotesInPoll)
+	}
+}
+
+// createAnonymousTestPoll creates anonymous poll of the type with options a, b, c.
+func createAnonymousTestPoll(t *testing.T, s *Service, pollType entity.PollType) *entity.Poll {
+	t.Helper()
+
+	poll, _, err := s.PollService.CreatePoll(context.Background(), entity.Poll{
+		Name:        "poll",
+		Creator:     testCreator,
+		Channel:     testChannel,
+		Type:        pollType,
+		IsAnonymous: true,
+	}, []entity.Option{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	if err != nil {
+		t.Fatalf("failed to create poll: %v", err)
+	}
+
+	return poll
+}
+
+func TestVoteService_AnonymousVote(t *testing.T) {
+	ctx := context.Background()
+	r := memory.NewRepo()
+	s := NewService(r, "secret")
+	poll := createAnonymousTestPoll(t, s, entity.PollTypeRanked)
+
+	receipt, err := s.VoteService.Vote(ctx, poll.ID, "voter", testChannel, []uint64{2, 1}, nil)
+	if err != nil {
+		t.Fatalf("Vote() error = %v", err)
+	}
+	if receipt == "" {
+		t.Fatal("Vote() returned no receipt for anonymous poll")
+	}
+
+	if _, err := s.VoteService.Vote(ctx, poll.ID, "voter", testChannel, []uint64{3}, nil); !errors.Is(err, ErrAlreadyVoted) {
+		t.Errorf("Vote() again error = %v, want %v", err, ErrAlreadyVoted)
+	}
+
+	votes, err := r.GetVotes(ctx, poll.ID)
+	if err != nil {
+		t.Fatalf("failed to get votes: %v", err)
+	}
+	if len(votes) != 1 || votes[0].User != "" || !slices.Equal(votes[0].OptionIDs, []uint64{2, 1}) {
+		t.Errorf("votes = %+v, want one ballot [2 1] without user", votes)
+	}
+
+	if _, err := s.VoteService.GetUserVote(ctx, poll.ID, "voter", testChannel); !errors.Is(err, ErrAnonymousChoice) {
+		t.Errorf("GetUserVote() error = %v, want %v", err, ErrAnonymousChoice)
+	}
+	if _, err := s.VoteService.ToggleOption(ctx, poll.ID, "voter", testChannel, 3); !errors.Is(err, ErrAnonymousChoice) {
+		t.Errorf("ToggleOption() error = %v, want %v", err, ErrAnonymousChoice)
+	}
+
+	if err := s.VoteService.RetractVote(ctx, poll.ID, "voter", testChannel, receipt); err != nil {
+		t.Fatalf("RetractVote() error = %v", err)
+	}
+	if _, err := s.VoteService.GetResults(ctx, poll.ID, testChannel); !errors.Is(err, ErrNoVotesInPoll) {
+		t.Errorf("GetResults() after retraction error = %v, want %v", err, ErrNoVotesInPoll)
+	}
+
+	// Ballot ID is random, so the same voter gets another one.
+	again, err := s.VoteService.Vote(ctx, poll.ID, "voter", testChannel, []uint64{2, 1}, nil)
+	if err != nil {
+		t.Fatalf("Vote() after retraction error = %v", err)
+	}
+	if again == receipt {
+		t.Errorf("Vote() after retraction returned the same receipt %q", receipt)
+	}
+}
+
+func TestVoteService_RetractAnonymousVote(t *testing.T) {
+	tests := []struct {
+		name string
+		// receipt forms receipt given for retraction from receipts of voter and other.
+		receipt func(voter, other string) string
+		wantErr error
+	}{
+		{
+			name:    "receipt of the voter",
+			receipt: func(voter, _ string) string { return voter },
+		},
+		{
+			name:    "receipt with spaces",
+			receipt: func(voter, _ string) string { return " " + voter + "\n" },
+		},
+		{
+			name:    "without receipt",
+			receipt: func(_, _ string) string { return "" },
+			wantErr: ErrInvalidReceipt,
+		},
+		{
+			name:    "receipt of other voter",
+			receipt: func(_, other string) string { return other },
+			wantErr: ErrInvalidReceipt,
+		},
+		{
+			name: "ballot of other voter with signature of the voter",
+			receipt: func(voter, other string) string {
+				_, signature, _ := strings.Cut(voter, ".")
+				ballotID, _, _ := strings.Cut(other, ".")
+				return ballotID + "." + signature
+			},
+			wantErr: ErrInvalidReceipt,
+		},
+		{
+			name:    "garbage",
+			receipt: func(_, _ string) string { return "receipt" },
+			wantErr: ErrInvalidReceipt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewService(memory.NewRepo(), "secret")
+			poll := createAnonymousTestPoll(t, s, entity.PollTypeSingle)
+
+			voter, err := s.VoteService.Vote(ctx, poll.ID, "voter", testChannel, []uint64{1}, nil)
+			if err != nil {
+				t.Fatalf("Vote() error = %v", err)
+			}
+			other, err := s.VoteService.Vote(ctx, poll.ID, "other", testChannel, []uint64{2}, nil)
+			if err != nil {
+				t.Fatalf("Vote() error = %v", err)
+			}
+
+			err = s.VoteService.RetractVote(ctx, poll.ID, "voter", testChannel, tt.receipt(voter, other))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RetractVote() error = %v, want %v", err, tt.wantErr)
+			}
+
+			results, err := s.VoteService.GetResults(ctx, poll.ID, testChannel)
+			if err != nil {
+				t.Fatalf("GetResults() error = %v", err)
+			}
+			// Vote of the other voter is kept in any case.
+			wantVotes := []uint64{1, 1, 0}
+			if tt.wantErr == nil {
+				wantVotes[0] = 0
+			}
+			for i, opt := range results.Options {
+				if opt.Votes != wantVotes[i] {
+					t.Errorf("option %d has %d votes, want %d", opt.Option.Num, opt.Votes, wantVotes[i])
+				}
+			}
+		})
+	}
+}
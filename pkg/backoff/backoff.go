@@ -0,0 +1,53 @@
+package backoff
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff calculates delays between retries.
+// Delay is doubled after every attempt up to Max, and a random jitter
+// is applied to it, so that clients don't retry all at once.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+
+	attempt uint
+}
+
+func New(min, max time.Duration) *Backoff {
+	return &Backoff{Min: min, Max: max}
+}
+
+// Next returns delay before the next attempt.
+// It's random in range [d/2, d], where d is exponential delay of the attempt.
+func (b *Backoff) Next() time.Duration {
+	d := b.Min
+	// Doubling stops at Max, so that delay doesn't overflow.
+	for i := uint(0); i < b.attempt && d > 0 && d < b.Max; i++ {
+		if d > b.Max/2 {
+			d = b.Max
+			break
+		}
+		d *= 2
+	}
+	d = min(d, b.Max)
+	b.attempt++
+
+	if d <= 1 {
+		return d
+	}
+
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Attempt returns number of delays returned since the last reset.
+func (b *Backoff) Attempt() uint {
+	return b.attempt
+}
+
+// Reset starts delays over from Min.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
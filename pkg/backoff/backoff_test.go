@@ -0,0 +1,81 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max time.Duration
+		// want[i] is exponential delay of attempt i, delay is in range [want[i]/2, want[i]].
+		want []time.Duration
+	}{
+		{
+			name: "doubles up to max",
+			min:  time.Second,
+			max:  10 * time.Second,
+			want: []time.Duration{
+				time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+				10 * time.Second, 10 * time.Second,
+			},
+		},
+		{
+			name: "min above max",
+			min:  time.Minute,
+			max:  time.Second,
+			want: []time.Duration{time.Second, time.Second},
+		},
+		{
+			name: "zero min",
+			min:  0,
+			max:  time.Second,
+			want: []time.Duration{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.min, tt.max)
+			for i, want := range tt.want {
+				if got := b.Next(); got < want/2 || got > want {
+					t.Errorf("Next() of attempt %d = %v, want in [%v, %v]", i, got, want/2, want)
+				}
+			}
+			if got := b.Attempt(); got != uint(len(tt.want)) {
+				t.Errorf("Attempt() = %d, want %d", got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestBackoff_NextDoesNotOverflow(t *testing.T) {
+	b := New(5*time.Second, time.Hour)
+
+	for i := 0; i < 100; i++ {
+		d := b.Next()
+		if d <= 0 || d > time.Hour {
+			t.Fatalf("Next() of attempt %d = %v, want in (0, %v]", i, d, time.Hour)
+		}
+		if i >= 10 && d < time.Hour/2 {
+			t.Fatalf("Next() of attempt %d = %v, want at least %v once max is reached", i, d, time.Hour/2)
+		}
+	}
+}
+
+func TestBackoff_Reset(t *testing.T) {
+	b := New(time.Second, time.Minute)
+	for i := 0; i < 10; i++ {
+		b.Next()
+	}
+
+	b.Reset()
+
+	if got := b.Attempt(); got != 0 {
+		t.Errorf("Attempt() after Reset() = %d, want 0", got)
+	}
+	if got := b.Next(); got < time.Second/2 || got > time.Second {
+		t.Errorf("Next() after Reset() = %v, want in [%v, %v]", got, time.Second/2, time.Second)
+	}
+}
@@ -1,7 +1,67 @@
+local clock = require('clock')
+local digest = require('digest')
+
 -- Create spaces --
 box.schema.space.create('polls', { if_not_exists = true })
 box.schema.space.create('options', { if_not_exists = true })
 box.schema.space.create('votes', { if_not_exists = true })
+box.schema.space.create('anonymous_voters', { if_not_exists = true })
+box.schema.space.create('ballots', { if_not_exists = true })
+box.schema.space.create('channel_cursors', { if_not_exists = true })
+box.schema.space.create('processed_commands', { if_not_exists = true })
+box.schema.space.create('leases', { if_not_exists = true })
+box.schema.space.create('poll_edits', { if_not_exists = true })
+
+-- Migrations --
+-- is_multi_vote boolean field was replaced with poll_type string field.
+-- Format is reset first, otherwise strings can't be stored there.
+box.once('polls_poll_type', function()
+    if box.space.polls.index.primary == nil then
+        return
+    end
+    box.space.polls:format({})
+    for _, poll in box.space.polls:pairs() do
+        box.space.polls:update(poll[1], {{'=', 6, poll[6] and 'multi' or 'single'}})
+    end
+end)
+
+-- Fields of reopened polls were added after others, so older polls get them as nulls,
+-- otherwise they can't be assigned by update.
+box.once('polls_reopen_fields', function()
+    if box.space.polls.index.primary == nil then
+        return
+    end
+    local ids = {}
+    for _, poll in box.space.polls:pairs() do
+        table.insert(ids, poll[1])
+    end
+    for _, id in ipairs(ids) do
+        local ops = {}
+        for i = #box.space.polls:get(id) + 1, 15 do
+            table.insert(ops, {'=', i, box.NULL})
+        end
+        if #ops > 0 then
+            box.space.polls:update(id, ops)
+        end
+    end
+end)
+
+-- Ballot ids were keyed hashes of voters, so the ballot of the voter could be found with the secret.
+-- They are replaced with random ids, voters of these ballots can't retract them anymore.
+box.once('ballots_random_ids', function()
+    if box.space.ballots.index.primary == nil then
+        return
+    end
+    local ballots = box.space.ballots:select()
+    box.atomic(function()
+        for _, ballot in ipairs(ballots) do
+            local new_ballot = ballot:totable()
+            new_ballot[1] = string.hex(digest.urandom(16))
+            box.space.ballots:delete{ballot[1]}
+            box.space.ballots:insert(new_ballot)
+        end
+    end)
+end)
 
 -- Specify field names and types --
 box.space.polls:format({
@@ -10,7 +70,17 @@ box.space.polls:format({
     {name = 'creator', type = 'string'},
     {name = 'channel', type = 'string'},
     {name = 'is_finished', type = 'boolean', default = false},
-    {name = 'is_multi_vote', type = 'boolean'}
+    {name = 'poll_type', type = 'string'},
+    {name = 'post_id', type = 'string', is_nullable = true},
+    {name = 'closes_at', type = 'unsigned', is_nullable = true},
+    {name = 'is_anonymous', type = 'boolean', is_nullable = true},
+    {name = 'salt', type = 'string', is_nullable = true},
+    {name = 'max_choices', type = 'unsigned', is_nullable = true},
+    {name = 'show_voters', type = 'boolean', is_nullable = true},
+    -- post with results posted when poll was finished by deadline
+    {name = 'results_post_id', type = 'string', is_nullable = true},
+    {name = 'reopened_by', type = 'string', is_nullable = true},
+    {name = 'reopened_at', type = 'unsigned', is_nullable = true}
 })
 
 box.space.options:format({
@@ -24,23 +94,96 @@ box.space.votes:format({
     {name = 'id', type = 'unsigned'},
     {name = 'user', type = 'string'},
     {name = 'poll_id', type = 'unsigned'},
-    {name = 'option_nums', type = 'array'}
+    {name = 'option_nums', type = 'array'},
+    -- option_values[i] is a score given to option_nums[i] in score polls
+    {name = 'option_values', type = 'array', is_nullable = true}
+})
+
+-- Voters and ballots of anonymous polls are kept apart,
+-- so that nobody could see who voted for what.
+-- Ballot id is random and ballots have no timestamps, so a ballot can't be linked
+-- to its voter even with the secret of the bot. Only the voter gets a receipt
+-- with the ballot id, which is needed to retract the vote.
+box.space.anonymous_voters:format({
+    {name = 'poll_id', type = 'unsigned'},
+    {name = 'voter_hash', type = 'string'}
+})
+
+box.space.ballots:format({
+    {name = 'id', type = 'string'},
+    {name = 'poll_id', type = 'unsigned'},
+    {name = 'option_nums', type = 'array'},
+    {name = 'option_values', type = 'array', is_nullable = true}
+})
+
+-- High-water marks of posts handled by the bot, used to replay missed commands.
+-- post_ids are IDs of handled posts created in the same millisecond as last_post_at.
+box.space.channel_cursors:format({
+    {name = 'channel', type = 'string'},
+    {name = 'last_post_at', type = 'unsigned'},
+    {name = 'post_ids', type = 'array'}
+})
+
+-- Outcomes of commands by ID of the post, so that the same post isn't handled twice.
+-- status is one of processing, done (response isn't sent yet) and sent.
+box.space.processed_commands:format({
+    {name = 'post_id', type = 'string'},
+    {name = 'channel', type = 'string'},
+    {name = 'processed_at', type = 'unsigned'},
+    {name = 'status', type = 'string'},
+    {name = 'response', type = 'string', is_nullable = true},
+    {name = 'is_public', type = 'boolean', is_nullable = true}
+})
+
+-- Leases are held by one bot replica at a time, e.g. leadership.
+-- expires_at is in unix milliseconds by tarantool clock.
+box.space.leases:format({
+    {name = 'name', type = 'string'},
+    {name = 'holder', type = 'string'},
+    {name = 'expires_at', type = 'unsigned'}
+})
+
+-- History of changes of polls made after they were created.
+-- option_num is a number of the edited option before the edit, null for edits of the poll itself.
+box.space.poll_edits:format({
+    {name = 'id', type = 'unsigned'},
+    {name = 'poll_id', type = 'unsigned'},
+    {name = 'editor', type = 'string'},
+    {name = 'edited_at', type = 'unsigned'},
+    {name = 'action', type = 'string'},
+    {name = 'option_num', type = 'unsigned', is_nullable = true},
+    {name = 'old_value', type = 'string', is_nullable = true},
+    {name = 'new_value', type = 'string', is_nullable = true}
 })
 
 -- Create sequences --
 box.schema.sequence.create('poll_id', { if_not_exists = true })
 box.schema.sequence.create('option_id', { if_not_exists = true })
 box.schema.sequence.create('vote_id', { if_not_exists = true })
+box.schema.sequence.create('poll_edit_id', { if_not_exists = true })
 
 -- Primary
 box.space.polls:create_index('primary', { parts = { 'id' }, sequence = 'poll_id', if_not_exists = true })
 box.space.options:create_index('primary', { parts = { 'id' }, sequence = 'option_id', if_not_exists = true })
 box.space.votes:create_index('primary', { parts = { 'id' }, sequence = 'vote_id', if_not_exists = true })
+box.space.anonymous_voters:create_index('primary', { parts = { 'poll_id', 'voter_hash' }, if_not_exists = true })
+box.space.ballots:create_index('primary', { parts = { 'id' }, if_not_exists = true })
+box.space.channel_cursors:create_index('primary', { parts = { 'channel' }, if_not_exists = true })
+box.space.processed_commands:create_index('primary', { parts = { 'post_id' }, if_not_exists = true })
+box.space.leases:create_index('primary', { parts = { 'name' }, if_not_exists = true })
+box.space.poll_edits:create_index('primary', { parts = { 'id' }, sequence = 'poll_edit_id', if_not_exists = true })
 
 -- Secondary
+box.space.polls:create_index('poll_closes_at', { unique = false, parts = { { 'closes_at', is_nullable = true } }, if_not_exists = true })
+box.space.polls:create_index('poll_channel_id', { unique = true, parts = { 'channel', 'id' }, if_not_exists = true })
+box.space.polls:create_index('poll_channel_status_id', { unique = true, parts = { 'channel', 'is_finished', 'id' }, if_not_exists = true })
+box.space.polls:create_index('poll_creator_id', { unique = true, parts = { 'creator', 'id' }, if_not_exists = true })
 box.space.options:create_index('option_poll_id', { unique = false, parts = { 'poll_id' }, if_not_exists = true })
 box.space.votes:create_index('vote_poll_id', { unique = false, parts = { 'poll_id' }, if_not_exists = true })
 box.space.votes:create_index('vote_user_poll_id', { unique = true, parts = {'user', 'poll_id'}, if_not_exists = true })
+box.space.ballots:create_index('ballot_poll_id', { unique = false, parts = { 'poll_id' }, if_not_exists = true })
+box.space.poll_edits:create_index('poll_edit_poll_id', { unique = false, parts = { 'poll_id' }, if_not_exists = true })
+box.space.processed_commands:create_index('command_processed_at', { unique = false, parts = { 'processed_at' }, if_not_exists = true })
 
 -- Add helper functions --
 -- For options deletion
@@ -63,17 +206,263 @@ box.schema.func.create('delete_votes', { if_not_exists = true })
 
 -- For votes creation
 -- (in case vote exists it's updated)
-function create_vote(user, poll_id, new_option_ids)
+function create_vote(user, poll_id, new_option_ids, new_option_values)
     local existing = box.space.votes.index.vote_user_poll_id:get{user, poll_id}
 
     if existing then
-        box.space.votes:update(existing.id, {{'=', 4, new_option_ids}})
+        box.space.votes:replace{existing.id, user, poll_id, new_option_ids, new_option_values}
         return box.space.votes:get{existing.id}
     else 
         local new_id = box.sequence.vote_id:next()
-        box.space.votes:insert{new_id, user, poll_id, new_option_ids}
+        box.space.votes:insert{new_id, user, poll_id, new_option_ids, new_option_values}
         return box.space.votes:get{new_id}
     end
 end
 
 box.schema.func.create('create_vote', { if_not_exists = true })
+
+-- For votes creation in anonymous polls
+-- (returns nothing in case voter has already voted, since his ballot can't be replaced)
+function create_anonymous_vote(poll_id, voter_hash, ballot_id, new_option_ids, new_option_values)
+    local ballot
+    box.atomic(function()
+        if box.space.anonymous_voters:get{poll_id, voter_hash} ~= nil then
+            return
+        end
+        box.space.anonymous_voters:insert{poll_id, voter_hash}
+        ballot = box.space.ballots:insert{ballot_id, poll_id, new_option_ids, new_option_values}
+    end)
+    if ballot == nil then
+        return
+    end
+    return ballot
+end
+
+box.schema.func.create('create_anonymous_vote', { if_not_exists = true })
+
+-- For votes deletion in anonymous polls
+-- (returns false in case user hasn't voted; ballot of another poll isn't deleted)
+function delete_anonymous_vote(poll_id, voter_hash, ballot_id)
+    local voter
+    box.atomic(function()
+        voter = box.space.anonymous_voters:delete{poll_id, voter_hash}
+        local ballot = box.space.ballots:get{ballot_id}
+        if voter ~= nil and ballot ~= nil and ballot.poll_id == poll_id then
+            box.space.ballots:delete{ballot_id}
+        end
+    end)
+    return voter ~= nil
+end
+
+box.schema.func.create('delete_anonymous_vote', { if_not_exists = true })
+
+-- For deletion of all votes in anonymous poll
+function delete_anonymous_votes(poll_id)
+    for _, voter in box.space.anonymous_voters:pairs(poll_id) do
+        box.space.anonymous_voters:delete{voter.poll_id, voter.voter_hash}
+    end
+    for _, ballot in box.space.ballots.index.ballot_poll_id:pairs(poll_id) do
+        box.space.ballots:delete{ballot.id}
+    end
+end
+
+box.schema.func.create('delete_anonymous_votes', { if_not_exists = true })
+
+-- For restoring deadlines of polls on startup
+-- (nulls are less than any number, so polls without deadline are skipped)
+function get_pending_polls()
+    local polls = {}
+    for _, poll in box.space.polls.index.poll_closes_at:pairs(0, { iterator = 'GE' }) do
+        if not poll.is_finished then
+            table.insert(polls, poll)
+        end
+    end
+    return polls
+end
+
+box.schema.func.create('get_pending_polls', { if_not_exists = true })
+
+-- For counting people who voted in the poll
+-- (ballots are counted in anonymous polls, since voters of ballots emptied by edits are kept)
+local function count_voters(poll)
+    if poll.is_anonymous then
+        return box.space.ballots.index.ballot_poll_id:count(poll.id)
+    end
+    return box.space.votes.index.vote_poll_id:count(poll.id)
+end
+
+-- For listing polls from the newest to the oldest
+-- (key is a prefix of the index before id, e.g. {channel};
+-- polls with id less than before_id are returned, all of them in case it's null;
+-- every poll is returned as a pair of the poll and number of its voters)
+function list_polls(index_name, key, before_id, limit)
+    local index = box.space.polls.index[index_name]
+    local from, iterator = key, 'LE'
+    if before_id ~= nil then
+        from = {unpack(key)}
+        table.insert(from, before_id)
+        iterator = 'LT'
+    end
+
+    local polls = {}
+    for _, poll in index:pairs(from, { iterator = iterator }) do
+        -- Iterator goes on to the other keys once polls of the key are over.
+        for i, value in ipairs(key) do
+            if poll[index.parts[i].fieldno] ~= value then
+                return polls
+            end
+        end
+        table.insert(polls, {poll, count_voters(poll)})
+        if #polls >= limit then
+            break
+        end
+    end
+    return polls
+end
+
+box.schema.func.create('list_polls', { if_not_exists = true })
+
+-- For adding option to the poll
+-- (option gets the next number, since options are numbered from 1 without gaps)
+function add_option(poll_id, name)
+    local num = box.space.options.index.option_poll_id:count(poll_id) + 1
+    return box.space.options:insert{box.sequence.option_id:next(), poll_id, name, num}
+end
+
+box.schema.func.create('add_option', { if_not_exists = true })
+
+-- For renaming option of the poll
+-- (returns false in case the poll doesn't have option with the number)
+function rename_option(poll_id, num, name)
+    for _, option in box.space.options.index.option_poll_id:pairs(poll_id) do
+        if option.option_num == num then
+            box.space.options:update(option.id, {{'=', 'option_name', name}})
+            return true
+        end
+    end
+    return false
+end
+
+box.schema.func.create('rename_option', { if_not_exists = true })
+
+-- Renumbers options chosen in vote or ballot, options with zero number are dropped
+-- (scores of score polls are dropped together with their options)
+local function renumber_choice(option_nums, option_values, nums)
+    local new_nums = {}
+    local new_values = box.NULL
+    if option_values ~= nil then
+        new_values = {}
+    end
+    for i, num in ipairs(option_nums) do
+        local new_num = nums[num]
+        if new_num ~= nil and new_num ~= 0 then
+            table.insert(new_nums, new_num)
+            if option_values ~= nil then
+                table.insert(new_values, option_values[i])
+            end
+        end
+    end
+    return new_nums, new_values
+end
+
+-- For removing and reordering options of the poll
+-- (nums[i] is a new number of option i, options with zero number are removed;
+-- options in votes and ballots are renumbered too, ones left without options are deleted;
+-- returns votes which lost options, ballots aren't returned since they don't have voters)
+function renumber_options(poll_id, nums)
+    for _, option in ipairs(box.space.options.index.option_poll_id:select(poll_id)) do
+        local num = nums[option.option_num]
+        if num == 0 then
+            box.space.options:delete{option.id}
+        else
+            box.space.options:update(option.id, {{'=', 'option_num', num}})
+        end
+    end
+
+    local changed = {}
+    for _, vote in ipairs(box.space.votes.index.vote_poll_id:select(poll_id)) do
+        local new_nums, new_values = renumber_choice(vote.option_nums, vote.option_values, nums)
+        if #new_nums == 0 then
+            box.space.votes:delete{vote.id}
+        else
+            box.space.votes:replace{vote.id, vote.user, poll_id, new_nums, new_values}
+        end
+        if #new_nums < #vote.option_nums then
+            table.insert(changed, {vote.id, vote.user, poll_id, new_nums, new_values})
+        end
+    end
+
+    -- Voters of empty ballots are kept, since they can't be found by ballot,
+    -- so voters of anonymous polls are counted by ballots.
+    for _, ballot in ipairs(box.space.ballots.index.ballot_poll_id:select(poll_id)) do
+        local new_nums, new_values = renumber_choice(ballot.option_nums, ballot.option_values, nums)
+        if #new_nums == 0 then
+            box.space.ballots:delete{ballot.id}
+        else
+            box.space.ballots:replace{ballot.id, poll_id, new_nums, new_values}
+        end
+    end
+
+    return changed
+end
+
+box.schema.func.create('renumber_options', { if_not_exists = true })
+
+-- For deletion of history of the poll
+function delete_poll_edits(poll_id)
+    for _, edit in ipairs(box.space.poll_edits.index.poll_edit_poll_id:select(poll_id)) do
+        box.space.poll_edits:delete{edit.id}
+    end
+end
+
+box.schema.func.create('delete_poll_edits', { if_not_exists = true })
+
+-- For claiming command before it's executed
+-- (returns the command claimed before, nil in case the post wasn't handled yet)
+function claim_command(post_id, channel, processed_at)
+    local existing = box.space.processed_commands:get{post_id}
+    if existing then
+        return existing
+    end
+    box.space.processed_commands:insert{post_id, channel, processed_at, 'processing'}
+    return nil
+end
+
+box.schema.func.create('claim_command', { if_not_exists = true })
+
+-- For cleanup of processed commands
+-- (returns number of deleted commands)
+function delete_processed_commands(before)
+    local deleted = 0
+    for _, command in box.space.processed_commands.index.command_processed_at:pairs(before, { iterator = 'LT' }) do
+        box.space.processed_commands:delete{command.post_id}
+        deleted = deleted + 1
+    end
+    return deleted
+end
+
+box.schema.func.create('delete_processed_commands', { if_not_exists = true })
+
+-- For leader election
+-- (lease is taken in case it's free, expired or already held by the holder)
+function acquire_lease(name, holder, ttl_ms)
+    local now = math.floor(clock.realtime() * 1000)
+    local lease = box.space.leases:get{name}
+    if lease and lease.holder ~= holder and lease.expires_at > now then
+        return false
+    end
+    box.space.leases:replace{name, holder, now + ttl_ms}
+    return true
+end
+
+box.schema.func.create('acquire_lease', { if_not_exists = true })
+
+-- For stepping down of the leader
+function release_lease(name, holder)
+    local lease = box.space.leases:get{name}
+    if lease and lease.holder == holder then
+        box.space.leases:delete{name}
+    end
+end
+
+box.schema.func.create('release_lease', { if_not_exists = true })